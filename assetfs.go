@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ASSETROOT          = "assets"
+	EMBEDDEDLAYER      = "embedded"
+	DATADIRLAYER       = "data-dir"
+	DEFAULTMODSDIR     = "mods"
+	MODLAYERPREFIX     = "mod:"
+	MAXLISTEDCONFLICTS = 20
+)
+
+// GameAssets is where every asset is loaded from. It starts as the embedded assets and setupAssets layers the data
+// directory and any mods over them.
+var GameAssets fs.FS = EmbeddedAssets
+
+// assetLayer is one source of assets, named for reporting
+type assetLayer struct {
+	name string
+	fsys fs.FS
+}
+
+// overlayFS looks for each file in its layers in order, so earlier layers override later ones. Directory listings are
+// merged across every layer.
+type overlayFS struct {
+	layers []assetLayer
+}
+
+func (overlay *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range overlay.layers {
+		file, err := layer.fsys.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", layer.name, err)
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (overlay *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var merged []fs.DirEntry
+	found := false
+	for _, layer := range overlay.layers {
+		entries, err := fs.ReadDir(layer.fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", layer.name, err)
+		}
+		found = true
+		for _, entry := range entries {
+			if !slices.ContainsFunc(merged, func(other fs.DirEntry) bool { return other.Name() == entry.Name() }) {
+				merged = append(merged, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	slices.SortFunc(merged, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return merged, nil
+}
+
+// mountedFS makes a directory laid out like assets appear under assets/, the same as the embedded files
+type mountedFS struct {
+	mountPoint string
+	fsys       fs.FS
+}
+
+func (mounted mountedFS) Open(name string) (fs.File, error) {
+	if name == mounted.mountPoint {
+		return mounted.fsys.Open(".")
+	}
+	rest, ok := strings.CutPrefix(name, mounted.mountPoint+"/")
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return mounted.fsys.Open(rest)
+}
+
+// setupAssets layers dataDir, then every mod folder in modsDir, over the embedded assets. Either directory may be
+// empty to leave it out, and a missing mods folder is not an error. Mods are loaded in name order with later mods
+// taking priority, and the data directory overrides them all.
+func setupAssets(dataDir string, modsDir string) error {
+	var layers []assetLayer
+	if dataDir != "" {
+		info, err := os.Stat(dataDir)
+		if err != nil {
+			return fmt.Errorf("data directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("data directory %s is not a directory", dataDir)
+		}
+		layers = append(layers, assetLayer{name: DATADIRLAYER, fsys: mountedFS{ASSETROOT, os.DirFS(dataDir)}})
+	}
+
+	if modsDir != "" {
+		entries, err := os.ReadDir(modsDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("reading mods folder: %w", err)
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !entries[i].IsDir() {
+				continue
+			}
+			layers = append(layers, assetLayer{
+				name: MODLAYERPREFIX + entries[i].Name(),
+				fsys: mountedFS{ASSETROOT, os.DirFS(filepath.Join(modsDir, entries[i].Name()))},
+			})
+		}
+	}
+
+	if len(layers) == 0 {
+		GameAssets = EmbeddedAssets
+		return nil
+	}
+	reportAssetLayers(layers)
+	GameAssets = &overlayFS{layers: append(layers, assetLayer{name: EMBEDDEDLAYER, fsys: EmbeddedAssets})}
+	return nil
+}
+
+// reportAssetLayers logs every mod that was loaded and any file that more than one of them provides
+func reportAssetLayers(layers []assetLayer) {
+	providers := map[string][]string{}
+	for _, layer := range layers {
+		files, replaced := 0, 0
+		err := fs.WalkDir(layer.fsys, ASSETROOT, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			files++
+			if _, err := fs.Stat(EmbeddedAssets, name); err == nil {
+				replaced++
+			}
+			providers[name] = append(providers[name], layer.name)
+			return nil
+		})
+		if err != nil {
+			gameLog.Warn("reading mod", "mod", layer.name, "err", err)
+		}
+		gameLog.Info("loaded mod", "mod", layer.name, "files", files, "replaced", replaced)
+	}
+
+	conflicts := make([]string, 0)
+	for name, layerNames := range providers {
+		if len(layerNames) > 1 {
+			conflicts = append(conflicts, name)
+		}
+	}
+	slices.Sort(conflicts)
+	for i, name := range conflicts {
+		if i == MAXLISTEDCONFLICTS {
+			gameLog.Warn("more mod conflicts not listed", "count", len(conflicts)-i)
+			break
+		}
+		//layers are in priority order, so the first is the one used
+		gameLog.Warn("mods conflict", "file", name, "using", providers[name][0],
+			"ignoring", strings.Join(providers[name][1:], ", "))
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/lafriks/go-tiled"
 	"github.com/solarlune/paths"
-	"image"
 )
 
 type character struct {
@@ -13,6 +12,7 @@ type character struct {
 	xLoc               int
 	yLoc               int
 	hitPoints          int
+	maxHitPoints       int
 	inventory          []item
 	direction          int
 	frame              int
@@ -23,16 +23,20 @@ type character struct {
 	imageYOffset       int
 	speed              int
 	level              *tiled.Map
-	interactRect       image.Rectangle
 	interactCooldown   int
 	attackPower        int
 	path               *paths.Path
 	pathUpdateCooldown int
+	characterType      int
+	knockbackX         float64
+	knockbackY         float64
+	invulnerableTimer  int
+	rangedCooldown     int
+	statusEffects      []statusEffect
 }
 
 // character method
 func (character *character) isPlayerInAttackRange(player *player) bool {
-	player.updatePlayerInteractionRectangle()
 	npcBounds := character.getCollisionBoundingBox()
 	playerBounds := player.getCollisionBoundingBox()
 
@@ -44,11 +48,13 @@ func (character *character) isPlayerInAttackRange(player *player) bool {
 
 // character
 func (character *character) death(game *rpgGame) {
+	combatLog.Info("enemy killed", "type", characterTypeNames[character.characterType], "xp", xpRewards[character.characterType])
 	character.dropAllItems(game)
+	game.playSoundAt("enemyDeath", character.level, character.centerX(), character.centerY())
 	character.xLoc = -100
 	character.yLoc = -100
 	character.action = DEAD
-	game.sounds.enemyDeath.playSound()
+	game.playerGainExperience(xpRewards[character.characterType])
 }
 
 // character method
@@ -81,29 +87,13 @@ func (character *character) getCollisionBoundingBox() collision.BoundingBox {
 
 // character
 func (character *character) dropAllItems(game *rpgGame) {
-	for i := range character.inventory {
-		character.dropItem(game, i)
+	drops := character.inventory
+	character.inventory = nil
+	if table, ok := lootTables[character.characterType]; ok {
+		drops = append(drops, table.roll(game.random.stream(RNGLOOT))...)
 	}
-	character.dropItem(game, -1)
-}
 
-// character
-func (character *character) dropItem(game *rpgGame, itemIndex int) {
-	//character.inventory[itemIndex] = nil
-	if itemIndex < 0 {
-		heart := HeartItem
-		heart.xLoc = character.xLoc + 20
-		heart.yLoc = character.yLoc + 20
-		heart.level = character.level
-		game.droppedItems = append(game.droppedItems, heart)
-	} else {
-		droppedItem := character.inventory[itemIndex]
-		droppedItem.xLoc = character.xLoc + 40
-		droppedItem.yLoc = character.yLoc + 40
-		droppedItem.level = character.level
-		game.droppedItems = append(game.droppedItems, droppedItem)
-		character.removeInventoryItemAtIndex(itemIndex)
-	}
+	game.scatterItems(drops, character.level, character.centerX(), character.centerY())
 }
 
 // player
@@ -137,6 +127,6 @@ func (character *character) moveCharacter(x, y int) {
 	} else if x > 0 || y > 0 {
 		character.direction = CHARACTLEFT
 	}
-	character.xLoc += x * character.speed
-	character.yLoc += y * character.speed
+	character.xLoc += x * character.effectiveSpeed()
+	character.yLoc += y * character.effectiveSpeed()
 }
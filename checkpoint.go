@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/co0p/tankism/lib/collision"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/colornames"
+	"image"
+)
+
+const (
+	PENALTYNONE = iota
+	PENALTYDROPINVENTORY
+	PENALTYLOSEEXPERIENCE
+)
+
+const CHECKPOINTOBJECTTYPE = "checkpoint"
+
+// checkpoint is a campfire placed in a map's object layer with the type "checkpoint". Positions are on screen,
+// already scaled up from map pixels.
+type checkpoint struct {
+	name       string
+	xLoc       int
+	yLoc       int
+	width      int
+	height     int
+	level      *tiled.Map
+	frame      int
+	frameDelay int
+}
+
+// respawnPoint is where the player comes back after dying or getting lost, levelIndex being into levelMaps
+type respawnPoint struct {
+	levelIndex int
+	xLoc       int
+	yLoc       int
+}
+
+var campfireFrames = []image.Image{
+	grabItemImage(64, 48, 16, 16),
+	grabItemImage(80, 48, 16, 16),
+	grabItemImage(96, 48, 16, 16),
+	grabItemImage(112, 48, 16, 16),
+	grabItemImage(128, 48, 16, 16),
+	grabItemImage(144, 48, 16, 16),
+	grabItemImage(160, 48, 16, 16),
+}
+
+// loadCheckpoints finds every checkpoint object in tiledMap's object layers
+func loadCheckpoints(tiledMap *tiled.Map) []checkpoint {
+	checkpoints := make([]checkpoint, 0)
+	for _, group := range tiledMap.ObjectGroups {
+		for _, object := range group.Objects {
+			if object.Type != CHECKPOINTOBJECTTYPE && object.Class != CHECKPOINTOBJECTTYPE {
+				continue
+			}
+			checkpoints = append(checkpoints, checkpoint{
+				name:   object.Name,
+				xLoc:   int(object.X) * worldScale,
+				yLoc:   int(object.Y) * worldScale,
+				width:  int(object.Width) * worldScale,
+				height: int(object.Height) * worldScale,
+				level:  tiledMap,
+			})
+		}
+	}
+	return checkpoints
+}
+
+func (checkpoint *checkpoint) getCollisionBoundingBox() collision.BoundingBox {
+	return collision.BoundingBox{
+		X:      float64(checkpoint.xLoc),
+		Y:      float64(checkpoint.yLoc),
+		Width:  float64(checkpoint.width),
+		Height: float64(checkpoint.height),
+	}
+}
+
+// checkpointInteractCheck lights any checkpoint in front of the player, healing them and moving their respawn point
+func (game *rpgGame) checkpointInteractCheck() {
+	probe := game.player.getInteractionProbe()
+	for i := range game.checkpoints {
+		checkpoint := &game.checkpoints[i]
+		if checkpoint.level != game.levelCurrent ||
+			!collision.AABBCollision(probe, checkpoint.getCollisionBoundingBox()) {
+			continue
+		}
+		game.player.heal(game.player.maxHitPoints)
+		game.respawn = respawnPoint{
+			levelIndex: game.levelIndex(checkpoint.level),
+			xLoc:       checkpoint.xLoc + checkpoint.width/2 - (game.player.FRAME_WIDTH*resizeScale)/2,
+			yLoc:       checkpoint.yLoc + checkpoint.height,
+		}
+		game.sounds.play("heal")
+		game.spawnFloatingText(&game.player.character, game.levelCurrent, "Checkpoint", colornames.Orange)
+		return
+	}
+}
+
+// moveToRespawnPoint puts the player back at their respawn point, changing maps if they need to
+func (game *rpgGame) moveToRespawnPoint() {
+	if game.levelIndex(game.levelCurrent) != game.respawn.levelIndex {
+		game.setLevel(game.respawn.levelIndex)
+	}
+	game.player.xLoc = game.respawn.xLoc
+	game.player.yLoc = game.respawn.yLoc
+}
+
+// respawnPlayer brings the player back at their last checkpoint after a game over, applying the death penalty
+func (game *rpgGame) respawnPlayer() {
+	switch game.settings.DeathPenalty {
+	case PENALTYDROPINVENTORY:
+		//leave everything where they fell for a corpse run
+		game.scatterItems(game.player.inventory, game.levelCurrent, game.player.centerX(), game.player.centerY())
+		game.player.inventory = nil
+	case PENALTYLOSEEXPERIENCE:
+		game.player.experience = game.levelCurve[game.player.experienceLevel-1].Experience
+	}
+
+	game.moveToRespawnPoint()
+	game.player.hitPoints = game.player.maxHitPoints
+	game.player.action = WALK
+	game.player.statusEffects = nil
+	game.player.knockbackX, game.player.knockbackY = 0, 0
+	game.player.invulnerableTimer = INVULNERABLEFRAMES
+	game.projectiles = nil
+}
+
+func (game *rpgGame) animateCheckpoints() {
+	for i := range game.checkpoints {
+		game.checkpoints[i].frameDelay++
+		if game.checkpoints[i].frameDelay%6 == 0 {
+			game.checkpoints[i].frame = (game.checkpoints[i].frame + 1) % len(campfireFrames)
+		}
+	}
+}
+
+func (game *rpgGame) drawCheckpoints(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
+	for _, checkpoint := range game.checkpoints {
+		if checkpoint.level == game.levelCurrent {
+			op.GeoM.Reset()
+			op.GeoM.Scale(worldScale, worldScale)
+			op.GeoM.Translate(float64(checkpoint.xLoc), float64(checkpoint.yLoc))
+			drawWorldImage(screen, campfireFrames[checkpoint.frame].(*ebiten.Image), op)
+		}
+	}
+}
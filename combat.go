@@ -0,0 +1,175 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+const (
+	INVULNERABLEFRAMES   = 45
+	KNOCKBACKSTRENGTH    = 12.0
+	KNOCKBACKFRICTION    = 0.8
+	DAMAGENUMBERLIFETIME = 40
+)
+
+// damageNumber is the text that floats up from whoever was just hit
+type damageNumber struct {
+	text   string
+	xLoc   int
+	yLoc   int
+	timer  int
+	color  color.Color
+	level  *tiled.Map
+	offset int
+}
+
+// damagePlayer hurts the player unless they are still invulnerable from the last hit, pushing them away from
+// (fromX, fromY). It reports whether the hit landed.
+func (game *rpgGame) damagePlayer(amount int, fromX, fromY int) bool {
+	if game.player.invulnerableTimer > 0 || game.godMode {
+		return false
+	}
+	if amount > 0 {
+		amount = max(1, int(math.Round(float64(amount)*game.difficulty().damageTaken)))
+	}
+	game.playSoundAt("playerDamaged", game.levelCurrent, fromX, fromY)
+	game.player.hitPoints -= amount
+	combatLog.Debug("player damaged", "amount", amount, "hitPoints", game.player.hitPoints)
+	game.player.invulnerableTimer = INVULNERABLEFRAMES
+	game.player.applyKnockback(fromX, fromY)
+	game.spawnDamageNumber(&game.player.character, game.levelCurrent, amount, colornames.Red)
+	return true
+}
+
+// damageEnemy hurts enemy and pushes it away from (fromX, fromY), killing it once its hitPoints run out. It reports
+// whether the hit landed, which it doesn't while the enemy is invulnerable.
+func (game *rpgGame) damageEnemy(enemy *character, amount int, fromX, fromY int) bool {
+	if enemy.invulnerableTimer > 0 {
+		return false
+	}
+	enemy.hitPoints -= amount
+	combatLog.Debug("enemy damaged", "type", characterTypeNames[enemy.characterType], "amount", amount, "hitPoints", enemy.hitPoints)
+	enemy.invulnerableTimer = INVULNERABLEFRAMES / 3
+	game.playSoundAt("enemyHit", enemy.level, enemy.centerX(), enemy.centerY())
+	game.spawnDamageNumber(enemy, enemy.level, amount, colornames.White)
+	if enemy.hitPoints <= 0 {
+		enemy.death(game)
+	} else {
+		enemy.applyKnockback(fromX, fromY)
+	}
+	return true
+}
+
+// applyKnockback gives character an impulse pointing away from (fromX, fromY)
+func (character *character) applyKnockback(fromX, fromY int) {
+	dx := float64(character.centerX() - fromX)
+	dy := float64(character.centerY() - fromY)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		//standing exactly on top of each other, just push upwards
+		dy, length = -1, 1
+	}
+	character.knockbackX = dx / length * KNOCKBACKSTRENGTH
+	character.knockbackY = dy / length * KNOCKBACKSTRENGTH
+}
+
+// isKnockedBack reports whether character is still sliding from a hit and shouldn't move on its own
+func (character *character) isKnockedBack() bool {
+	return math.Abs(character.knockbackX) >= 1 || math.Abs(character.knockbackY) >= 1
+}
+
+// updateKnockback slides character along its impulse one axis at a time, stopping an axis that runs into a barrier
+func (character *character) updateKnockback(barrierRects []image.Rectangle) {
+	if !character.isKnockedBack() {
+		character.knockbackX, character.knockbackY = 0, 0
+		return
+	}
+	stepX := int(character.knockbackX)
+	character.xLoc += stepX
+	if isBorderColliding(barrierRects, character) {
+		character.xLoc -= stepX
+		character.knockbackX = 0
+	}
+	stepY := int(character.knockbackY)
+	character.yLoc += stepY
+	if isBorderColliding(barrierRects, character) {
+		character.yLoc -= stepY
+		character.knockbackY = 0
+	}
+	character.knockbackX *= KNOCKBACKFRICTION
+	character.knockbackY *= KNOCKBACKFRICTION
+}
+
+func (character *character) centerX() int {
+	return character.xLoc + (character.FRAME_WIDTH*resizeScale)/2
+}
+
+func (character *character) centerY() int {
+	return character.yLoc + (character.FRAME_HEIGHT*resizeScale)/2
+}
+
+// isFlashing is true on the frames a recently hit character should be hidden to blink
+func (character *character) isFlashing() bool {
+	return character.invulnerableTimer > 0 && (character.invulnerableTimer/4)%2 == 0
+}
+
+// updateCombatEffects ticks knockback, invulnerability and damage numbers once per Update
+func (game *rpgGame) updateCombatEffects() {
+	game.player.updateKnockback(game.barrierRect)
+	if game.player.invulnerableTimer > 0 {
+		game.player.invulnerableTimer--
+	}
+	if game.player.levelUpTimer > 0 {
+		game.player.levelUpTimer--
+	}
+	for i := range game.enemies {
+		if game.enemies[i].level == game.levelCurrent && game.enemies[i].action != DEAD {
+			game.enemies[i].updateKnockback(game.barrierRect)
+		}
+		if game.enemies[i].invulnerableTimer > 0 {
+			game.enemies[i].invulnerableTimer--
+		}
+	}
+
+	remaining := game.damageNumbers[:0]
+	for _, number := range game.damageNumbers {
+		number.timer--
+		if number.timer%2 == 0 {
+			number.offset++
+		}
+		if number.timer > 0 {
+			remaining = append(remaining, number)
+		}
+	}
+	game.damageNumbers = remaining
+}
+
+func (game *rpgGame) spawnDamageNumber(target *character, level *tiled.Map, amount int, textColor color.Color) {
+	game.spawnFloatingText(target, level, strconv.Itoa(amount), textColor)
+}
+
+// spawnFloatingText floats s up from above target, the same way damage numbers do
+func (game *rpgGame) spawnFloatingText(target *character, level *tiled.Map, s string, textColor color.Color) {
+	game.damageNumbers = append(game.damageNumbers, damageNumber{
+		text:  s,
+		xLoc:  target.centerX(),
+		yLoc:  target.yLoc,
+		timer: DAMAGENUMBERLIFETIME,
+		color: textColor,
+		level: level,
+	})
+}
+
+func (game *rpgGame) drawDamageNumbers(screen *ebiten.Image) {
+	for _, number := range game.damageNumbers {
+		if number.level == game.levelCurrent {
+			DrawCenteredTextColor(screen, game.fontSmall, number.text, number.xLoc/worldScale,
+				(number.yLoc-number.offset)/worldScale, number.color)
+		}
+	}
+}
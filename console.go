@@ -0,0 +1,467 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	CONSOLETOGGLEKEY       = ebiten.KeyGraveAccent
+	CONSOLEHISTORYFILENAME = "console_history.txt"
+	CONSOLEHISTORYLIMIT    = 100
+	CONSOLEOUTPUTLIMIT     = 200
+	CONSOLEVISIBLELINES    = 20
+	CONSOLELINEHEIGHT      = 16
+	CONSOLEMARGIN          = 8
+	CONSOLESPAWNOFFSET     = 60
+)
+
+var questStageNames = map[int]string{
+	NOTTALKED:    "nottalked",
+	TALKED:       "talked",
+	RETURNEDITEM: "returned",
+}
+
+// consoleCommand is something that can be typed into the developer console. run gets the words after the name and
+// returns what to print.
+type consoleCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(game *rpgGame, args []string) (string, error)
+}
+
+// consoleCommands is every command the console knows, by name
+var consoleCommands = map[string]consoleCommand{}
+
+// registerConsoleCommand adds command to the developer console. Subsystems call it from an init function so their
+// commands are there before the game starts.
+func registerConsoleCommand(command consoleCommand) {
+	if _, ok := consoleCommands[command.name]; ok {
+		panic("console command registered twice: " + command.name)
+	}
+	consoleCommands[command.name] = command
+}
+
+// errConsoleUsage makes a command print its usage line
+var errConsoleUsage = errors.New("wrong arguments")
+
+// developerConsole is a scene for typing commands over the world, opened with the key left of 1. The world is paused
+// while it is open. What it printed and the command history are kept for the next time it opens, and the history is
+// saved next to the settings.
+type developerConsole struct {
+	input        string
+	output       []string
+	history      []string
+	historyIndex int
+	//set while the key that opened the console may still be arriving as a typed character
+	opening bool
+}
+
+// consoleScene is the developer console, loading its history the first time it is opened. It is called as the
+// console is opened.
+func (game *rpgGame) consoleScene() *developerConsole {
+	if game.console == nil {
+		history := loadConsoleHistory()
+		game.console = &developerConsole{
+			history:      history,
+			historyIndex: len(history),
+			output:       []string{"type help for a list of commands"},
+		}
+	}
+	game.console.opening = true
+	return game.console
+}
+
+func consoleHistoryPath() string {
+	return filepath.Join(filepath.Dir(settingsPath()), CONSOLEHISTORYFILENAME)
+}
+
+func loadConsoleHistory() []string {
+	data, err := os.ReadFile(consoleHistoryPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			gameLog.Warn("reading console history", "err", err)
+		}
+		return nil
+	}
+	return strings.FieldsFunc(string(data), func(r rune) bool { return r == '\n' || r == '\r' })
+}
+
+func (console *developerConsole) saveHistory() {
+	filePath := consoleHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		gameLog.Warn("saving console history", "err", err)
+		return
+	}
+	data := strings.Join(console.history, "\n") + "\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		gameLog.Warn("saving console history", "err", err)
+	}
+}
+
+func (console *developerConsole) update(game *rpgGame) error {
+	if game.keyJustPressed(ebiten.KeyEscape) || game.keyJustPressed(CONSOLETOGGLEKEY) {
+		game.popScene()
+		return nil
+	}
+	for _, typed := range game.typedChars() {
+		//the toggle key types a character too, which can come in a tick after the key that opened the console
+		if console.opening && (typed == '`' || typed == '~') {
+			continue
+		}
+		console.input += string(typed)
+	}
+	console.opening = false
+	if game.keyRepeated(ebiten.KeyBackspace) && len(console.input) > 0 {
+		runes := []rune(console.input)
+		console.input = string(runes[:len(runes)-1])
+	}
+	if game.keyJustPressed(ebiten.KeyArrowUp) && console.historyIndex > 0 {
+		console.historyIndex--
+		console.input = console.history[console.historyIndex]
+	} else if game.keyJustPressed(ebiten.KeyArrowDown) && console.historyIndex < len(console.history) {
+		console.historyIndex++
+		console.input = ""
+		if console.historyIndex < len(console.history) {
+			console.input = console.history[console.historyIndex]
+		}
+	}
+	if game.keyJustPressed(ebiten.KeyEnter) || game.keyJustPressed(ebiten.KeyNumpadEnter) {
+		console.execute(game, console.input)
+		console.input = ""
+	}
+	return nil
+}
+
+// execute runs line as a command, remembering it in the history and printing what it returns
+func (console *developerConsole) execute(game *rpgGame, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if len(console.history) == 0 || console.history[len(console.history)-1] != line {
+		console.history = append(console.history, line)
+		if len(console.history) > CONSOLEHISTORYLIMIT {
+			console.history = console.history[len(console.history)-CONSOLEHISTORYLIMIT:]
+		}
+		console.saveHistory()
+	}
+	console.historyIndex = len(console.history)
+	console.print("> " + line)
+
+	words := strings.Fields(line)
+	command, ok := consoleCommands[strings.ToLower(words[0])]
+	if !ok {
+		console.print("unknown command " + words[0] + ", type help for a list")
+		return
+	}
+	gameLog.Debug("console command", "command", line)
+	result, err := command.run(game, words[1:])
+	if errors.Is(err, errConsoleUsage) {
+		console.print("usage: " + command.name + " " + command.usage)
+	} else if err != nil {
+		console.print("error: " + err.Error())
+	}
+	if result != "" {
+		console.print(result)
+	}
+}
+
+// print adds text to the output, one entry per line, dropping the oldest once there are too many
+func (console *developerConsole) print(text string) {
+	console.output = append(console.output, strings.Split(text, "\n")...)
+	if len(console.output) > CONSOLEOUTPUTLIMIT {
+		console.output = console.output[len(console.output)-CONSOLEOUTPUTLIMIT:]
+	}
+}
+
+// draw leaves the logical screen alone, the console is drawn by drawOverlay where its text has room
+func (console *developerConsole) draw(game *rpgGame, screen *ebiten.Image) {}
+
+// drawOverlay draws the console across the top of the logical screen at the window's own resolution
+func (console *developerConsole) drawOverlay(game *rpgGame, screen *ebiten.Image) {
+	x := int(game.view.offsetX)
+	y := int(game.view.offsetY)
+	width := float32(float64(game.logicalWidth) * game.view.scale)
+	height := (CONSOLEVISIBLELINES+1)*CONSOLELINEHEIGHT + CONSOLEMARGIN*2
+	vector.DrawFilledRect(screen, float32(x), float32(y), width, float32(height), color.RGBA{A: 200}, false)
+	lines := console.output[max(len(console.output)-CONSOLEVISIBLELINES, 0):]
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), x+CONSOLEMARGIN, y+CONSOLEMARGIN)
+	ebitenutil.DebugPrintAt(screen, "] "+console.input+"_", x+CONSOLEMARGIN,
+		y+CONSOLEMARGIN+CONSOLEVISIBLELINES*CONSOLELINEHEIGHT)
+}
+
+// parseConsoleInts reads every argument as a whole number
+func parseConsoleInts(args []string) ([]int, error) {
+	numbers := make([]int, len(args))
+	for i, arg := range args {
+		number, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", arg)
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}
+
+// consolePosition is the x and y in args, or somewhere just in front of the player if there aren't any
+func (game *rpgGame) consolePosition(args []string) (image.Point, error) {
+	if len(args) == 0 {
+		offset := CONSOLESPAWNOFFSET * game.player.translateDirectionToPositiveNegative()
+		if game.player.direction == UP || game.player.direction == DOWN {
+			return image.Pt(game.player.xLoc, game.player.yLoc+offset), nil
+		}
+		return image.Pt(game.player.xLoc+offset, game.player.yLoc), nil
+	}
+	if len(args) != 2 {
+		return image.Point{}, errConsoleUsage
+	}
+	numbers, err := parseConsoleInts(args)
+	if err != nil {
+		return image.Point{}, err
+	}
+	return image.Pt(numbers[0], numbers[1]), nil
+}
+
+// levelByName finds a level from its index in levelMaps or its file name, with or without .tmx
+func levelByName(name string) (int, error) {
+	if index, err := strconv.Atoi(name); err == nil {
+		if index < 0 || index >= len(levelFiles) {
+			return 0, fmt.Errorf("there is no map %d", index)
+		}
+		return index, nil
+	}
+	for index, filename := range levelFiles {
+		if strings.EqualFold(filename, name) || strings.EqualFold(strings.TrimSuffix(filename, ".tmx"), name) {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("there is no map called %s, try one of %s", name, strings.Join(levelFiles, ", "))
+}
+
+// nameFor finds the name given to value in names
+func nameFor(names map[string]int, value int) string {
+	for name, named := range names {
+		if named == value {
+			return name
+		}
+	}
+	return strconv.Itoa(value)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func init() {
+	registerConsoleCommand(consoleCommand{
+		name:  "help",
+		usage: "[command]",
+		help:  "lists every command, or explains one",
+		run: func(game *rpgGame, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errConsoleUsage
+			}
+			if len(args) == 1 {
+				command, ok := consoleCommands[strings.ToLower(args[0])]
+				if !ok {
+					return "", fmt.Errorf("unknown command %s", args[0])
+				}
+				return command.name + " " + command.usage + "\n  " + command.help, nil
+			}
+			lines := make([]string, 0, len(consoleCommands))
+			for _, name := range sortedKeys(consoleCommands) {
+				lines = append(lines, name+" "+consoleCommands[name].usage)
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "teleport",
+		usage: "<map> [x y]",
+		help:  "moves the player to a map by number or name, at x and y on screen or where they are now",
+		run: func(game *rpgGame, args []string) (string, error) {
+			if len(args) != 1 && len(args) != 3 {
+				return "", errConsoleUsage
+			}
+			index, err := levelByName(args[0])
+			if err != nil {
+				return "", err
+			}
+			position := image.Pt(game.player.xLoc, game.player.yLoc)
+			if len(args) == 3 {
+				if position, err = game.consolePosition(args[1:]); err != nil {
+					return "", err
+				}
+			}
+			game.setLevel(index)
+			game.player.xLoc, game.player.yLoc = position.X, position.Y
+			return fmt.Sprintf("teleported to %s at %d,%d", levelFiles[index], position.X, position.Y), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "spawn",
+		usage: "enemy|item <id> [x y]",
+		help:  "puts a new enemy or item on this map, in front of the player unless x and y are given",
+		run: func(game *rpgGame, args []string) (string, error) {
+			if len(args) < 2 {
+				return "", errConsoleUsage
+			}
+			position, err := game.consolePosition(args[2:])
+			if err != nil {
+				return "", err
+			}
+			id := strings.ToLower(args[1])
+			switch strings.ToLower(args[0]) {
+			case "enemy":
+				enemyTypes := map[string]int{}
+				for characterType := range enemySpriteRows {
+					enemyTypes[characterTypeNames[characterType]] = characterType
+				}
+				characterType, ok := enemyTypes[id]
+				if !ok {
+					return "", fmt.Errorf("no enemy called %s, try one of %s", id,
+						strings.Join(sortedKeys(enemyTypes), ", "))
+				}
+				game.enemies = append(game.enemies, game.newEnemy(characterType, game.levelCurrent, position.X,
+					position.Y))
+			case "item":
+				spawned, ok := itemIDs[id]
+				if !ok {
+					return "", fmt.Errorf("no item called %s, try one of %s", id,
+						strings.Join(sortedKeys(itemIDs), ", "))
+				}
+				spawned.level = game.levelCurrent
+				spawned.xLoc, spawned.yLoc = position.X, position.Y
+				game.droppedItems = append(game.droppedItems, spawned)
+			default:
+				return "", errConsoleUsage
+			}
+			return fmt.Sprintf("spawned %s at %d,%d", id, position.X, position.Y), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "hp",
+		usage: "<hit points>",
+		help:  "sets the player's hit points, raising their maximum if needed",
+		run: func(game *rpgGame, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errConsoleUsage
+			}
+			numbers, err := parseConsoleInts(args)
+			if err != nil {
+				return "", err
+			}
+			if numbers[0] < 1 {
+				return "", errors.New("hit points must be at least 1")
+			}
+			game.player.hitPoints = numbers[0]
+			game.player.maxHitPoints = max(game.player.maxHitPoints, numbers[0])
+			return fmt.Sprintf("hit points %d/%d", game.player.hitPoints, game.player.maxHitPoints), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "attack",
+		usage: "<power>",
+		help:  "sets the player's attack power",
+		run: func(game *rpgGame, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errConsoleUsage
+			}
+			numbers, err := parseConsoleInts(args)
+			if err != nil {
+				return "", err
+			}
+			if numbers[0] < 0 {
+				return "", errors.New("attack power can't be negative")
+			}
+			game.player.attackPower = numbers[0]
+			return fmt.Sprintf("attack power %d", game.player.attackPower), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "quest",
+		usage: "[stage]",
+		help:  "shows or sets how far through the quest the player is: nottalked, talked or returned",
+		run: func(game *rpgGame, args []string) (string, error) {
+			stages := map[string]int{}
+			for stage, name := range questStageNames {
+				stages[name] = stage
+			}
+			if len(args) > 1 {
+				return "", errConsoleUsage
+			}
+			if len(args) == 1 {
+				stage, ok := stages[strings.ToLower(args[0])]
+				if !ok {
+					return "", fmt.Errorf("no quest stage called %s", args[0])
+				}
+				game.player.questProgress = stage
+				game.dialogueTicks = 0
+			}
+			return "quest stage " + nameFor(stages, game.player.questProgress), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "god",
+		usage: "",
+		help:  "toggles taking no damage",
+		run: func(game *rpgGame, args []string) (string, error) {
+			game.godMode = !game.godMode
+			return "god mode " + onOff(game.godMode), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "noclip",
+		usage: "",
+		help:  "toggles walking through barriers",
+		run: func(game *rpgGame, args []string) (string, error) {
+			game.noclip = !game.noclip
+			return "noclip " + onOff(game.noclip), nil
+		},
+	})
+	registerConsoleCommand(consoleCommand{
+		name:  "list",
+		usage: "",
+		help:  "lists the player, characters and items on every map",
+		run: func(game *rpgGame, args []string) (string, error) {
+			lines := []string{fmt.Sprintf("player map %d at %d,%d hp %d/%d", game.levelIndex(game.levelCurrent),
+				game.player.xLoc, game.player.yLoc, game.player.hitPoints, game.player.maxHitPoints)}
+			lines = append(lines, fmt.Sprintf("quest giver map %d at %d,%d", game.levelIndex(game.questGiver.level),
+				game.questGiver.xLoc, game.questGiver.yLoc))
+			for i, enemy := range game.enemies {
+				lines = append(lines, fmt.Sprintf("enemy %d %s map %d at %d,%d hp %d %s", i,
+					characterTypeNames[enemy.characterType], game.levelIndex(enemy.level), enemy.xLoc, enemy.yLoc,
+					enemy.hitPoints, actionNames[enemy.action]))
+			}
+			for _, droppedItem := range game.droppedItems {
+				lines = append(lines, fmt.Sprintf("item %s map %d at %d,%d", droppedItem.displayName,
+					game.levelIndex(droppedItem.level), droppedItem.xLoc, droppedItem.yLoc))
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"github.com/co0p/tankism/lib/collision"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	DEBUGTOGGLEKEY   = ebiten.KeyF3
+	DEBUGPANELWIDTH  = 250
+	DEBUGLINEHEIGHT  = 16
+	DEBUGPANELMARGIN = 8
+)
+
+var actionNames = map[int]string{
+	WALK:     "walk",
+	INTERACT: "interact",
+	PATH:     "path",
+	DEAD:     "dead",
+	STAY:     "stay",
+}
+
+var characterTypeNames = map[int]string{
+	NPC:        "npc",
+	MANNEQUIN:  "mannequin",
+	KING:       "king",
+	LEPRECHAUN: "leprechaun",
+}
+
+var statusNames = map[int]string{
+	POISON:       "poison",
+	SLOW:         "slow",
+	STUN:         "stun",
+	REGENERATION: "regen",
+}
+
+func init() {
+	registerConsoleCommand(consoleCommand{
+		name:  "debug",
+		usage: "",
+		help:  "toggles the debug overlay, the same as F3",
+		run: func(game *rpgGame, args []string) (string, error) {
+			game.debugOverlay = !game.debugOverlay
+			return "debug overlay " + onOff(game.debugOverlay), nil
+		},
+	})
+}
+
+// drawDebugOverlay draws hitboxes, map collision, enemy pathing and a panel of entity state over the world. It draws
+// straight onto the window, after the logical screen, so the panel's text stays readable at any scale.
+func (game *rpgGame) drawDebugOverlay(screen *ebiten.Image) {
+	for _, barrier := range game.barrierRect {
+		game.strokeTileRect(screen, barrier, colornames.Red)
+	}
+	for _, teleporter := range game.teleporterRects {
+		game.strokeTileRect(screen, teleporter, colornames.Magenta)
+	}
+	for _, missing := range game.missingTiles {
+		game.strokeTileRect(screen, missing, colornames.Yellow)
+	}
+	for _, checkpoint := range game.checkpoints {
+		if checkpoint.level == game.levelCurrent {
+			game.strokeBoundingBox(screen, checkpoint.getCollisionBoundingBox(), colornames.Orange)
+		}
+	}
+
+	for i := range game.enemies {
+		enemy := &game.enemies[i]
+		if enemy.level != game.levelCurrent || enemy.action == DEAD {
+			continue
+		}
+		game.drawEnemyPath(screen, enemy)
+		//enemies notice the player anywhere inside this square, see updateWorld
+		sightRange := float64(game.sightRange())
+		game.strokeBoundingBox(screen, collision.BoundingBox{
+			X:      float64(enemy.xLoc+enemy.FRAME_WIDTH/2) - sightRange,
+			Y:      float64(enemy.yLoc+enemy.FRAME_HEIGHT/2) - sightRange,
+			Width:  sightRange * 2,
+			Height: sightRange * 2,
+		}, color.RGBA{R: 255, G: 255, A: 96})
+		game.strokeBoundingBox(screen, enemy.getCollisionBoundingBox(), colornames.Lime)
+	}
+	if game.questGiver.level == game.levelCurrent {
+		game.strokeBoundingBox(screen, game.questGiver.getCollisionBoundingBox(), colornames.Lime)
+	}
+
+	game.strokeBoundingBox(screen, game.player.getCollisionBoundingBox(), colornames.Cyan)
+	game.strokeBoundingBox(screen, game.player.getInteractionProbe(), colornames.Yellow)
+	attackColor := color.Color(colornames.Gray)
+	if game.player.isAttackActive() {
+		attackColor = colornames.Orangered
+	}
+	game.strokeBoundingBox(screen, game.player.getAttackBoundingBox(), attackColor)
+
+	game.drawDebugPanel(screen)
+}
+
+// drawEnemyPath joins up the centres of the cells an enemy is still walking towards
+func (game *rpgGame) drawEnemyPath(screen *ebiten.Image, enemy *character) {
+	if enemy.path == nil {
+		return
+	}
+	tileWidth := float64(game.levelCurrent.TileWidth * worldScale)
+	tileHeight := float64(game.levelCurrent.TileHeight * worldScale)
+	lastX, lastY := game.view.toWindow(float64(enemy.centerX()), float64(enemy.centerY()))
+	for i := max(enemy.path.CurrentIndex, 0); i < len(enemy.path.Cells); i++ {
+		cell := enemy.path.Cells[i]
+		x, y := game.view.toWindow(float64(cell.X)*tileWidth+tileWidth/2, float64(cell.Y)*tileHeight+tileHeight/2)
+		vector.StrokeLine(screen, lastX, lastY, x, y, 2, colornames.Deepskyblue, false)
+		vector.DrawFilledCircle(screen, x, y, 3, colornames.Deepskyblue, false)
+		lastX, lastY = x, y
+	}
+}
+
+// drawDebugPanel lists frame rates and the state of the player and every enemy on this map in the top right corner
+func (game *rpgGame) drawDebugPanel(screen *ebiten.Image) {
+	lines := []string{
+		fmt.Sprintf("FPS %.1f  TPS %.1f", ebiten.ActualFPS(), ebiten.ActualTPS()),
+		fmt.Sprintf("map %d  player %d,%d", game.levelIndex(game.levelCurrent), game.player.xLoc, game.player.yLoc),
+		fmt.Sprintf("player %s hp %d/%d", actionNames[game.player.action], game.player.hitPoints,
+			game.player.maxHitPoints),
+		fmt.Sprintf(" cd %d ranged %d inv %d", game.player.interactCooldown, game.player.rangedCooldown,
+			game.player.invulnerableTimer),
+	}
+	for _, effect := range game.player.statusEffects {
+		lines = append(lines, fmt.Sprintf(" %s x%d %dt", statusNames[effect.kind], effect.stacks,
+			effect.remaining))
+	}
+	for i, enemy := range game.enemies {
+		if enemy.level != game.levelCurrent {
+			continue
+		}
+		lines = append(lines,
+			fmt.Sprintf("%d %s %s hp %d/%d", i, characterTypeNames[enemy.characterType], actionNames[enemy.action],
+				enemy.hitPoints, enemy.maxHitPoints),
+			fmt.Sprintf(" cd %d path %d ranged %d", enemy.interactCooldown, enemy.pathUpdateCooldown,
+				enemy.rangedCooldown))
+	}
+	if len(game.missingTiles) > 0 {
+		lines = append(lines, fmt.Sprintf("%d tiles missing images", len(game.missingTiles)))
+	}
+
+	//top right of the logical screen, in the window
+	right := int(game.view.offsetX + float64(game.logicalWidth)*game.view.scale)
+	top := int(game.view.offsetY) + DEBUGPANELMARGIN
+	x := right - DEBUGPANELWIDTH - DEBUGPANELMARGIN
+	height := len(lines)*DEBUGLINEHEIGHT + DEBUGPANELMARGIN
+	vector.DrawFilledRect(screen, float32(x), float32(top), DEBUGPANELWIDTH, float32(height), color.RGBA{A: 180},
+		false)
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), x+DEBUGPANELMARGIN/2, top)
+}
+
+// strokeTileRect outlines a rectangle given in map pixels, like the barrier and teleporter rects
+func (game *rpgGame) strokeTileRect(screen *ebiten.Image, rect image.Rectangle, strokeColor color.Color) {
+	game.strokeBoundingBox(screen, collision.BoundingBox{
+		X:      float64(rect.Min.X * worldScale),
+		Y:      float64(rect.Min.Y * worldScale),
+		Width:  float64(rect.Dx() * worldScale),
+		Height: float64(rect.Dy() * worldScale),
+	}, strokeColor)
+}
+
+// strokeBoundingBox outlines a box given in world pixels
+func (game *rpgGame) strokeBoundingBox(screen *ebiten.Image, box collision.BoundingBox, strokeColor color.Color) {
+	x, y := game.view.toWindow(box.X, box.Y)
+	vector.StrokeRect(screen, x, y, game.view.worldToWindowLength(box.Width),
+		game.view.worldToWindowLength(box.Height), 1, strokeColor, false)
+}
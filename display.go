@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+)
+
+// the logical screen is this many tiles across, whatever size the window is. It is drawn at the art's own
+// resolution, one logical pixel to a map pixel, and only ever scaled up by whole numbers so pixels stay square.
+const (
+	VIEWTILESWIDE = 15
+	VIEWTILESHIGH = 15
+)
+
+// viewport is where the logical screen ends up in the window, letterboxed and scaled up by a whole number when the
+// window is big enough for pixels to stay square
+type viewport struct {
+	scale   float64
+	offsetX float64
+	offsetY float64
+}
+
+// fitViewport scales a logical screen into an outside one, preferring whole number scales and centring the result
+func fitViewport(logicalWidth, logicalHeight, outsideWidth, outsideHeight int) viewport {
+	scale := min(float64(outsideWidth)/float64(logicalWidth), float64(outsideHeight)/float64(logicalHeight))
+	if scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	return viewport{
+		scale:   scale,
+		offsetX: math.Floor((float64(outsideWidth) - float64(logicalWidth)*scale) / 2),
+		offsetY: math.Floor((float64(outsideHeight) - float64(logicalHeight)*scale) / 2),
+	}
+}
+
+// toLogical turns a position in the window into one on the logical screen
+func (view viewport) toLogical(x, y int) (int, int) {
+	return int(math.Floor((float64(x) - view.offsetX) / view.scale)),
+		int(math.Floor((float64(y) - view.offsetY) / view.scale))
+}
+
+// toWindow turns a position in world pixels, the units positions and collision use, into one in the window. It is
+// for overlays drawn over the letterboxed screen at the window's own resolution.
+func (view viewport) toWindow(x, y float64) (float32, float32) {
+	return float32(view.offsetX + x/worldScale*view.scale), float32(view.offsetY + y/worldScale*view.scale)
+}
+
+// worldToWindowLength turns a length in world pixels into one in the window
+func (view viewport) worldToWindowLength(length float64) float32 {
+	return float32(length / worldScale * view.scale)
+}
+
+// drawWorldImage draws img onto the logical screen with op's transform given in world pixels. There are worldScale
+// world pixels to a logical one, so art scaled up by worldScale in the world is drawn at its own size.
+func drawWorldImage(screen *ebiten.Image, img *ebiten.Image, op *ebiten.DrawImageOptions) {
+	op.GeoM.Scale(1.0/worldScale, 1.0/worldScale)
+	screen.DrawImage(img, op)
+}
+
+// Layout works in device pixels so the logical screen can be scaled pixel perfectly on high DPI displays
+func (game *rpgGame) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	deviceScale := ebiten.Monitor().DeviceScaleFactor()
+	screenWidth = max(int(float64(outsideWidth)*deviceScale), 1)
+	screenHeight = max(int(float64(outsideHeight)*deviceScale), 1)
+	game.view = fitViewport(game.logicalWidth, game.logicalHeight, screenWidth, screenHeight)
+	return screenWidth, screenHeight
+}
+
+// overlayScene is a scene that also draws over the letterboxed screen at the window's own resolution, for developer
+// tools that need more room for text than the logical screen has
+type overlayScene interface {
+	drawOverlay(game *rpgGame, screen *ebiten.Image)
+}
+
+// drawLetterboxed draws the logical canvas onto the window with black bars around it
+func (game *rpgGame) drawLetterboxed(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(game.view.scale, game.view.scale)
+	op.GeoM.Translate(game.view.offsetX, game.view.offsetY)
+	if game.view.scale < 1 {
+		op.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(game.canvas, op)
+}
+
+// toggleFullscreen flips fullscreen and remembers the choice in the settings file
+func (game *rpgGame) toggleFullscreen() {
+	game.settings.Fullscreen = !game.settings.Fullscreen
+	ebiten.SetFullscreen(game.settings.Fullscreen)
+	game.saveSettings()
+}
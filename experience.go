@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/colornames"
+	"math"
+	"path"
+	"strconv"
+)
+
+const (
+	LEVELUPEFFECTLENGTH = 60
+	XPBARWIDTH          = 60
+	XPBARHEIGHT         = 6
+)
+
+// xpRewards is how much experience the player earns for killing each character type
+var xpRewards = map[int]int{
+	MANNEQUIN:  2,
+	KING:       5,
+	LEPRECHAUN: 3,
+}
+
+var levelUpSparkle = grabItemImage(176, 48, 16, 16)
+
+// levelGrowth is one entry of the curve in assets/data/levels.json. Experience is the running total needed to
+// reach the level, and the stats are added to the player when they do.
+type levelGrowth struct {
+	Experience   int `json:"experience"`
+	MaxHitPoints int `json:"maxHitPoints"`
+	AttackPower  int `json:"attackPower"`
+	Speed        int `json:"speed"`
+}
+
+type levelCurveFile struct {
+	Levels []levelGrowth `json:"levels"`
+}
+
+// loadLevelCurve reads the player's growth curve, the first entry being level 1
+func loadLevelCurve(name string) ([]levelGrowth, error) {
+	file, err := GameAssets.Open(path.Join("assets", "data", name))
+	if err != nil {
+		return nil, fmt.Errorf("loading level curve: %w", err)
+	}
+	defer file.Close()
+
+	var curve levelCurveFile
+	if err := json.NewDecoder(file).Decode(&curve); err != nil {
+		return nil, fmt.Errorf("interpreting level curve %s: %w", name, err)
+	}
+	if len(curve.Levels) == 0 {
+		return nil, fmt.Errorf("level curve %s has no levels", name)
+	}
+	return curve.Levels, nil
+}
+
+// playerGainExperience adds amount to the player's experience, levelling them up as many times as it pays for
+func (game *rpgGame) playerGainExperience(amount int) {
+	if amount <= 0 {
+		return
+	}
+	game.player.experience += amount
+	for game.player.experienceLevel < len(game.levelCurve) && game.player.experience >= game.levelCurve[game.player.experienceLevel].Experience {
+		growth := game.levelCurve[game.player.experienceLevel]
+		game.player.experienceLevel++
+		game.player.raiseMaxHealth(growth.MaxHitPoints)
+		game.player.attackPower += growth.AttackPower
+		game.player.speed += growth.Speed
+		game.player.levelUpTimer = LEVELUPEFFECTLENGTH
+		game.sounds.play("levelUp")
+		game.spawnFloatingText(&game.player.character, game.levelCurrent, "LEVEL UP!", colornames.Gold)
+	}
+}
+
+// experienceProgress returns how far the player is through their current level, from 0 to 1
+func (game *rpgGame) experienceProgress() float64 {
+	if game.player.experienceLevel >= len(game.levelCurve) {
+		return 1
+	}
+	previous := game.levelCurve[game.player.experienceLevel-1].Experience
+	next := game.levelCurve[game.player.experienceLevel].Experience
+	if next <= previous {
+		return 1
+	}
+	return float64(game.player.experience-previous) / float64(next-previous)
+}
+
+// drawExperienceBar draws the player's level and progress to the next one beside the "Power:" text
+func (game *rpgGame) drawExperienceBar(screen *ebiten.Image) {
+	hudY := game.logicalHeight - HUDMARGIN
+	DrawCenteredText(screen, game.fontSmall, "Lv"+strconv.Itoa(game.player.experienceLevel), 88, hudY)
+	barX := float32(104)
+	barY := float32(hudY - XPBARHEIGHT/2)
+	vector.DrawFilledRect(screen, barX, barY, XPBARWIDTH, XPBARHEIGHT, colornames.Black, false)
+	vector.DrawFilledRect(screen, barX, barY, XPBARWIDTH*float32(game.experienceProgress()), XPBARHEIGHT,
+		colornames.Gold, false)
+	vector.StrokeRect(screen, barX, barY, XPBARWIDTH, XPBARHEIGHT, 1, colornames.White, false)
+}
+
+// drawLevelUpEffect circles sparkles around the player for a moment after they level up
+func (game *rpgGame) drawLevelUpEffect(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
+	if game.player.levelUpTimer <= 0 {
+		return
+	}
+	radius := float64(game.player.FRAME_HEIGHT*resizeScale) / 2
+	for i := 0; i < 4; i++ {
+		angle := float64(game.player.levelUpTimer)/10 + float64(i)*math.Pi/2
+		op.GeoM.Reset()
+		op.GeoM.Scale(resizeScale, resizeScale)
+		op.GeoM.Translate(float64(game.player.centerX())+math.Cos(angle)*radius-8*resizeScale,
+			float64(game.player.centerY())+math.Sin(angle)*radius-8*resizeScale)
+		drawWorldImage(screen, levelUpSparkle.(*ebiten.Image), op)
+	}
+}
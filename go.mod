@@ -1,6 +1,6 @@
 module Comp426_Project3p1_RPG
 
-go 1.21
+go 1.22
 
 require (
 	github.com/co0p/tankism v0.0.0-20230903205805-1ebc2e88a661
@@ -16,6 +16,8 @@ require (
 	github.com/ebitengine/oto/v3 v3.2.0 // indirect
 	github.com/ebitengine/purego v0.7.1 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
+	github.com/jfreymuth/oggvorbis v1.0.5 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+)
+
+const (
+	HEALTHPERHEART = 2 //hit points are counted in half hearts
+	HEARTSPERROW   = 10
+	HUDMARGIN      = 8 //from the bottom of the screen to the middle of the HUD text
+	HUDICONSIZE    = 16
+)
+
+const (
+	HEARTFULL = iota
+	HEARTHALF
+	HEARTEMPTY
+)
+
+var heartImages = [3]image.Image{
+	HEARTFULL:  grabItemImage(64, 0, 16, 16),
+	HEARTHALF:  grabItemImage(96, 0, 16, 16),
+	HEARTEMPTY: grabItemImage(128, 0, 16, 16),
+}
+
+// heal restores up to amount hit points without going over maxHitPoints, returning how much it healed
+func (character *character) heal(amount int) int {
+	healed := min(amount, character.maxHitPoints-character.hitPoints)
+	if healed <= 0 {
+		return 0
+	}
+	character.hitPoints += healed
+	return healed
+}
+
+// raiseMaxHealth grows maxHitPoints and fills the new space
+func (character *character) raiseMaxHealth(amount int) {
+	character.maxHitPoints += amount
+	character.hitPoints += amount
+}
+
+// healthRows is how many rows of hearts the HUD needs for the player's max hit points
+func (game *rpgGame) healthRows() int {
+	hearts := (game.player.maxHitPoints + HEALTHPERHEART - 1) / HEALTHPERHEART
+	return max((hearts+HEARTSPERROW-1)/HEARTSPERROW, 1)
+}
+
+// drawPlayerHealth draws one heart container per HEALTHPERHEART of max health, filled, half filled or empty,
+// wrapping onto a new row every HEARTSPERROW hearts
+func (game *rpgGame) drawPlayerHealth(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
+	hearts := (game.player.maxHitPoints + HEALTHPERHEART - 1) / HEALTHPERHEART
+	for i := 0; i < hearts; i++ {
+		remaining := game.player.hitPoints - i*HEALTHPERHEART
+		heart := HEARTEMPTY
+		if remaining >= HEALTHPERHEART {
+			heart = HEARTFULL
+		} else if remaining > 0 {
+			heart = HEARTHALF
+		}
+		op.GeoM.Reset()
+		op.GeoM.Translate(float64((i%HEARTSPERROW)*HUDICONSIZE), float64((i/HEARTSPERROW)*HUDICONSIZE))
+		screen.DrawImage(heartImages[heart].(*ebiten.Image), op)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	HOTRELOADPOLLTICKS = 30
+	DEVASSETSDIR       = "assets"
+)
+
+// watchedFile is a file under one of the watched folders, named by its path in assets
+type watchedFile struct {
+	assetName string
+	modTime   time.Time
+	size      int64
+}
+
+// assetWatcher polls folders laid out like assets for files that were added, changed or removed. It checks
+// modification times rather than using file system events so it needs nothing beyond the standard library.
+type assetWatcher struct {
+	directories []string
+	files       map[string]watchedFile
+	timer       int
+}
+
+func newAssetWatcher(directories []string) *assetWatcher {
+	watcher := &assetWatcher{directories: directories}
+	watcher.files = watcher.scan()
+	return watcher
+}
+
+// watchedDirectories are the folders on disk that assets can come from, the data directory and each mod
+func watchedDirectories(dataDir string, modsDir string) []string {
+	var directories []string
+	if dataDir != "" {
+		directories = append(directories, dataDir)
+	}
+	if entries, err := os.ReadDir(modsDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				directories = append(directories, filepath.Join(modsDir, entry.Name()))
+			}
+		}
+	}
+	return directories
+}
+
+// scan lists every file in the watched folders, keyed by where it is on disk
+func (watcher *assetWatcher) scan() map[string]watchedFile {
+	files := map[string]watchedFile{}
+	for _, directory := range watcher.directories {
+		err := filepath.WalkDir(directory, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			relative, err := filepath.Rel(directory, filePath)
+			if err != nil {
+				return err
+			}
+			files[filePath] = watchedFile{
+				assetName: path.Join(ASSETROOT, filepath.ToSlash(relative)),
+				modTime:   info.ModTime(),
+				size:      info.Size(),
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			warnRepeated(worldLog, "watching "+directory, "watching assets", "directory", directory, "err", err)
+		}
+	}
+	return files
+}
+
+// changedAssets polls every HOTRELOADPOLLTICKS calls and returns the asset names of files that changed since the
+// last poll
+func (watcher *assetWatcher) changedAssets() []string {
+	watcher.timer++
+	if watcher.timer%HOTRELOADPOLLTICKS != 0 {
+		return nil
+	}
+	files := watcher.scan()
+	var changed []string
+	for filePath, file := range files {
+		previous, ok := watcher.files[filePath]
+		if !ok || !previous.modTime.Equal(file.modTime) || previous.size != file.size {
+			changed = append(changed, file.assetName)
+		}
+	}
+	for filePath, file := range watcher.files {
+		if _, ok := files[filePath]; !ok {
+			changed = append(changed, file.assetName)
+		}
+	}
+	watcher.files = files
+	slices.Sort(changed)
+	return slices.Compact(changed)
+}
+
+// hotReload reloads whatever assets changed on disk since it last looked
+func (game *rpgGame) hotReload() {
+	if game.watcher == nil {
+		return
+	}
+	changed := game.watcher.changedAssets()
+	if len(changed) == 0 {
+		return
+	}
+
+	levels := map[int]bool{}
+	for _, name := range changed {
+		switch {
+		case path.Ext(name) == ".tmx":
+			if index := slices.Index(levelFiles, strings.TrimPrefix(name, ASSETROOT+"/")); index != -1 {
+				levels[index] = true
+			} else {
+				worldLog.Info("changed map isn't one of the game's levels", "file", name)
+			}
+		case path.Ext(name) == ".tsx":
+			//any map could use the tileset
+			for index := range levelFiles {
+				levels[index] = true
+			}
+		case path.Ext(name) == ".png" && game.levelsUsingTileset(name) != nil:
+			for _, index := range game.levelsUsingTileset(name) {
+				levels[index] = true
+			}
+		case path.Dir(name) == path.Join(ASSETROOT, "data") && path.Base(name) == "levels.json":
+			curve, err := loadLevelCurve(path.Base(name))
+			if err != nil {
+				worldLog.Error("reloading level curve", "err", err)
+				continue
+			}
+			game.levelCurve = curve
+			worldLog.Info("reloaded level curve", "levels", len(curve))
+		case path.Dir(name) == path.Join(ASSETROOT, "sounds") && path.Ext(name) == ".wav":
+			soundName := strings.TrimSuffix(path.Base(name), ".wav")
+			if err := game.sounds.loadEmbeddedWav(soundName, path.Base(name)); err != nil {
+				audioLog.Error("reloading sound", "err", err)
+				continue
+			}
+			audioLog.Info("reloaded sound", "sound", soundName)
+		default:
+			worldLog.Info("changed file can't be reloaded while the game runs", "file", name)
+		}
+	}
+
+	for index := range levels {
+		level, err := game.loadLevel(levelFiles[index])
+		if err != nil {
+			//keep playing on the old map until the file is fixed
+			worldLog.Error("reloading map", "file", levelFiles[index], "err", err)
+			continue
+		}
+		game.replaceLevel(index, level)
+		worldLog.Info("reloaded map", "file", levelFiles[index])
+	}
+}
+
+// levelsUsingTileset lists the levels whose tileset image is imageName
+func (game *rpgGame) levelsUsingTileset(imageName string) []int {
+	var indexes []int
+	for index, level := range game.levelMaps {
+		for _, tileset := range level.Tilesets {
+			if tileset.Image != nil && path.Join(ASSETROOT, tileset.Image.Source) == imageName {
+				indexes = append(indexes, index)
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// replaceLevel swaps the map at index for one that was just loaded. Everything on the old map is moved onto the new
+// one where it stands, so the game carries on as it was.
+func (game *rpgGame) replaceLevel(index int, level *loadedLevel) {
+	old := game.levelMaps[index]
+	game.levelMaps[index] = level.gameMap
+	game.tileHashes[index] = level.tiles
+	game.pathFindingMaps[index] = level.searchMap
+	game.pathGrids[index] = level.pathGrid
+	game.checkpoints = slices.DeleteFunc(game.checkpoints, func(c checkpoint) bool { return c.level == old })
+	game.checkpoints = append(game.checkpoints, level.checkpoints...)
+
+	for i := range game.enemies {
+		if game.enemies[i].level == old {
+			game.enemies[i].level = level.gameMap
+			//their path was worked out on the old grid
+			game.enemies[i].path = nil
+		}
+	}
+	if game.questGiver.level == old {
+		game.questGiver.level = level.gameMap
+	}
+	if game.player.level == old {
+		game.player.level = level.gameMap
+	}
+	for i := range game.droppedItems {
+		if game.droppedItems[i].level == old {
+			game.droppedItems[i].level = level.gameMap
+		}
+	}
+	for i := range game.projectiles {
+		if game.projectiles[i].level == old {
+			game.projectiles[i].level = level.gameMap
+		}
+	}
+	for i := range game.damageNumbers {
+		if game.damageNumbers[i].level == old {
+			game.damageNumbers[i].level = level.gameMap
+		}
+	}
+
+	if game.levelCurrent == old {
+		game.setLevel(index)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"image"
+	"slices"
+)
+
+const (
+	KEYREPEATDELAY = 30
+	KEYREPEATTICKS = 3
+)
+
+// inputFrame is everything the player did in one tick, with positions already on the logical screen. The game only
+// reads input through the frame for the current tick, so a recorded session can be fed back in exactly.
+type inputFrame struct {
+	Keys   []ebiten.Key  `json:"keys,omitempty"`
+	Chars  string        `json:"chars,omitempty"`
+	Cursor image.Point   `json:"cursor"`
+	Click  bool          `json:"click,omitempty"`
+	Taps   []image.Point `json:"taps,omitempty"`
+}
+
+// inputState is the current tick's frame along with how many ticks each key has been held for
+type inputState struct {
+	frame     inputFrame
+	durations map[ebiten.Key]int
+}
+
+// captureInputFrame reads what is being pressed right now from ebiten
+func (game *rpgGame) captureInputFrame() inputFrame {
+	frame := inputFrame{
+		Keys:   inpututil.AppendPressedKeys(nil),
+		Chars:  string(ebiten.AppendInputChars(nil)),
+		Cursor: image.Pt(game.view.toLogical(ebiten.CursorPosition())),
+		Click:  inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft),
+	}
+	slices.Sort(frame.Keys)
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		frame.Taps = append(frame.Taps, image.Pt(game.view.toLogical(ebiten.TouchPosition(id))))
+	}
+	return frame
+}
+
+// advance moves on to the next tick's input, counting up how long each key has been held
+func (input *inputState) advance(frame inputFrame) {
+	durations := make(map[ebiten.Key]int, len(frame.Keys))
+	for _, key := range frame.Keys {
+		durations[key] = input.durations[key] + 1
+	}
+	input.frame = frame
+	input.durations = durations
+}
+
+// keyPressed reports whether key is held down this tick
+func (game *rpgGame) keyPressed(key ebiten.Key) bool {
+	return game.input.durations[key] > 0
+}
+
+// keyJustPressed reports whether key went down this tick
+func (game *rpgGame) keyJustPressed(key ebiten.Key) bool {
+	return game.input.durations[key] == 1
+}
+
+// keyRepeated is true when key is first pressed, then repeatedly while it is held down, like typing
+func (game *rpgGame) keyRepeated(key ebiten.Key) bool {
+	duration := game.input.durations[key]
+	return duration == 1 || (duration >= KEYREPEATDELAY && duration%KEYREPEATTICKS == 0)
+}
+
+// justPressedKeys lists the keys that went down this tick
+func (game *rpgGame) justPressedKeys() []ebiten.Key {
+	var keys []ebiten.Key
+	for _, key := range game.input.frame.Keys {
+		if game.keyJustPressed(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// typedChars is the text typed this tick
+func (game *rpgGame) typedChars() string {
+	return game.input.frame.Chars
+}
+
+// cursorPosition is the mouse position on the logical screen
+func (game *rpgGame) cursorPosition() image.Point {
+	return game.input.frame.Cursor
+}
+
+// clicked reports whether the left mouse button went down this tick
+func (game *rpgGame) clicked() bool {
+	return game.input.frame.Click
+}
+
+// justTappedPositions are the logical positions of touches that started this tick
+func (game *rpgGame) justTappedPositions() []image.Point {
+	return game.input.frame.Taps
+}
@@ -13,10 +13,12 @@ type item struct {
 	yAnimationOffset int
 	delay            int
 	level            *tiled.Map
+	appliesStatus    int
+	raisesMaxHealth  int
 }
 
 var HeartItem = item{
-	picture:          grabItemImage(63, 0, 16, 16),
+	picture:          grabItemImage(64, 0, 16, 16),
 	displayName:      "Heart",
 	xLoc:             400,
 	yLoc:             100,
@@ -42,6 +44,35 @@ var StoneItem = item{
 	delay:            0,
 }
 
+var HeartContainerItem = item{
+	picture:          grabItemImage(32, 80, 16, 16),
+	displayName:      "Heart Container",
+	xLoc:             0,
+	yLoc:             0,
+	yAnimationOffset: 0,
+	delay:            0,
+	raisesMaxHealth:  HEALTHPERHEART,
+}
+
+var HerbItem = item{
+	picture:          grabItemImage(70, 22, 16, 16),
+	displayName:      "Herb",
+	xLoc:             300,
+	yLoc:             450,
+	yAnimationOffset: 0,
+	delay:            0,
+	appliesStatus:    REGENERATION,
+}
+
+// itemIDs names every item so they can be referred to by the developer console
+var itemIDs = map[string]item{
+	"heart":          HeartItem,
+	"book":           BookItem,
+	"stone":          StoneItem,
+	"heartcontainer": HeartContainerItem,
+	"herb":           HerbItem,
+}
+
 func (item *item) itemAnimate() {
 	item.delay++
 	if item.delay%6 == 0 {
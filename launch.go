@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// launchOptions are the command line flags that set up how this run starts, so a scenario can be jumped straight to
+type launchOptions struct {
+	startMap     string
+	spawn        string
+	windowScale  int
+	seed         int64
+	debugOverlay bool
+	skipTitle    bool
+	ticks        int
+}
+
+// launchStart is where startNewGame puts the player instead of the usual start, set from the launch options. nil
+// fields leave that part of the start as it is.
+type launchStart struct {
+	levelIndex *int
+	spawn      *image.Point
+}
+
+// parseSpawnPoint reads a position on screen written as x,y
+func parseSpawnPoint(spawn string) (image.Point, error) {
+	xText, yText, ok := strings.Cut(spawn, ",")
+	if !ok {
+		return image.Point{}, fmt.Errorf("spawn point %q should be written x,y", spawn)
+	}
+	x, xErr := strconv.Atoi(strings.TrimSpace(xText))
+	y, yErr := strconv.Atoi(strings.TrimSpace(yText))
+	if xErr != nil || yErr != nil {
+		return image.Point{}, fmt.Errorf("spawn point %q should be two whole numbers", spawn)
+	}
+	return image.Pt(x, y), nil
+}
+
+// applyLaunchOptions sets the game up as options ask, before it starts running. With skipTitle, or when running
+// headless, the game starts straight away instead of on the title screen.
+func (game *rpgGame) applyLaunchOptions(options launchOptions) error {
+	if options.windowScale != 0 {
+		if options.windowScale < MINWINDOWSCALE || options.windowScale > MAXWINDOWSCALE {
+			return fmt.Errorf("window scale must be between %d and %d", MINWINDOWSCALE, MAXWINDOWSCALE)
+		}
+		//only for this run, the saved setting is left alone
+		ebiten.SetWindowSize(game.logicalWidth*options.windowScale, game.logicalHeight*options.windowScale)
+	}
+	if options.ticks < 0 {
+		return errors.New("ticks can't be negative")
+	}
+
+	game.random = newGameRandom(chooseSeed(options.seed, game.settings.Seed))
+	gameLog.Info("random seed", "seed", game.random.seed)
+
+	if options.startMap != "" {
+		index, err := levelByName(options.startMap)
+		if err != nil {
+			return err
+		}
+		game.start.levelIndex = &index
+	}
+	if options.spawn != "" {
+		spawn, err := parseSpawnPoint(options.spawn)
+		if err != nil {
+			return err
+		}
+		game.start.spawn = &spawn
+	}
+
+	game.debugOverlay = options.debugOverlay
+	if options.skipTitle || options.ticks > 0 {
+		game.startNewGame()
+		game.replaceScenes(&gameplayScene{})
+	} else {
+		game.showTitle()
+	}
+	return nil
+}
+
+// moveToLaunchStart puts a new game's player on the map and spawn point from the launch options, if they gave any
+func (game *rpgGame) moveToLaunchStart() {
+	if game.start.levelIndex != nil {
+		game.setLevel(*game.start.levelIndex)
+		game.respawn.levelIndex = *game.start.levelIndex
+	}
+	if game.start.spawn != nil {
+		game.player.xLoc, game.player.yLoc = game.start.spawn.X, game.start.spawn.Y
+		game.respawn.xLoc, game.respawn.yLoc = game.start.spawn.X, game.start.spawn.Y
+	}
+}
+
+// runHeadless updates the game ticks times without a window or drawing anything, for scripted smoke tests. Nothing
+// is pressed, so the game plays out as if left alone. Collision comes from the map, not from drawing, so walls and
+// teleporters work the same as in a window.
+func (game *rpgGame) runHeadless(ticks int) error {
+	game.headless = true
+	for tick := 0; tick < ticks; tick++ {
+		if err := game.Update(); errors.Is(err, ebiten.Termination) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("tick %d: %w", tick, err)
+		}
+	}
+	gameLog.Info("headless run finished", "ticks", ticks, "map", game.levelIndex(game.levelCurrent),
+		"x", game.player.xLoc, "y", game.player.yLoc, "hitPoints", game.player.hitPoints)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHeadlessRunTeleports(t *testing.T) {
+	game := newTestGame(t)
+	game.setLevel(2)
+	var teleporterID uint32
+	for _, ID := range teleporterTileIDs {
+		if _, ok := game.teleporterRects[ID]; ok && teleporterDestinations[ID] != 2 {
+			teleporterID = ID
+			break
+		}
+	}
+	if teleporterID == 0 {
+		t.Fatal("the world map has no teleporters to another map")
+	}
+	teleporter := game.teleporterRects[teleporterID]
+	options := launchOptions{
+		startMap:  levelFiles[2],
+		spawn:     fmt.Sprintf("%d,%d", teleporter.Min.X*worldScale, teleporter.Min.Y*worldScale),
+		seed:      1,
+		skipTitle: true,
+	}
+	if err := game.applyLaunchOptions(options); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.runHeadless(1); err != nil {
+		t.Fatal(err)
+	}
+	if index := game.levelIndex(game.levelCurrent); index != teleporterDestinations[teleporterID] {
+		t.Errorf("standing on teleporter %d took the player to map %d, want %d", teleporterID, index,
+			teleporterDestinations[teleporterID])
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const REPEATEDWARNINGINTERVAL = 5 * time.Second
+
+// every subsystem logs through its own logger so its lines can be told apart and filtered
+var (
+	gameLog   = slog.Default().With("subsystem", "game")
+	worldLog  = slog.Default().With("subsystem", "world")
+	audioLog  = slog.Default().With("subsystem", "audio")
+	combatLog = slog.Default().With("subsystem", "combat")
+	aiLog     = slog.Default().With("subsystem", "ai")
+)
+
+// setupLogging points every subsystem logger at output, dropping anything below level. level is one of debug, info,
+// warn or error, and an empty fileName logs to stderr. The returned closer closes the log file.
+func setupLogging(level string, fileName string) (io.Closer, error) {
+	var minimum slog.Level
+	if err := minimum.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	var output io.WriteCloser = nopCloser{os.Stderr}
+	if fileName != "" {
+		file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening log file: %w", err)
+		}
+		output = file
+	}
+
+	logger := slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: minimum}))
+	slog.SetDefault(logger)
+	gameLog = logger.With("subsystem", "game")
+	worldLog = logger.With("subsystem", "world")
+	audioLog = logger.With("subsystem", "audio")
+	combatLog = logger.With("subsystem", "combat")
+	aiLog = logger.With("subsystem", "ai")
+	return output, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// repeatedWarnings remembers when each rate limited warning was last logged and how many were held back since
+var repeatedWarnings = struct {
+	sync.Mutex
+	lastLogged map[string]time.Time
+	suppressed map[string]int
+}{lastLogged: map[string]time.Time{}, suppressed: map[string]int{}}
+
+// warnRepeated logs a warning that could otherwise fire every frame at most once every REPEATEDWARNINGINTERVAL for
+// each key, counting how many times it was suppressed in between
+func warnRepeated(logger *slog.Logger, key string, msg string, args ...any) {
+	repeatedWarnings.Lock()
+	defer repeatedWarnings.Unlock()
+	now := time.Now()
+	if last, ok := repeatedWarnings.lastLogged[key]; ok && now.Sub(last) < REPEATEDWARNINGINTERVAL {
+		repeatedWarnings.suppressed[key]++
+		return
+	}
+	if suppressed := repeatedWarnings.suppressed[key]; suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+	repeatedWarnings.lastLogged[key] = now
+	repeatedWarnings.suppressed[key] = 0
+	logger.Warn(msg, args...)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/lafriks/go-tiled"
+	"image"
+	"math/rand/v2"
+	"slices"
+)
+
+const (
+	NPC = iota
+	MANNEQUIN
+	KING
+	LEPRECHAUN
+)
+
+// lootEntry is one possible drop in a lootTable, dropping between minQuantity and maxQuantity copies of item
+type lootEntry struct {
+	item        item
+	weight      int
+	minQuantity int
+	maxQuantity int
+}
+
+// lootTable describes what a character type drops on death. Guaranteed entries always drop, then entries are
+// rolled against each other and nothingWeight once per roll.
+type lootTable struct {
+	guaranteed    []lootEntry
+	entries       []lootEntry
+	nothingWeight int
+	rolls         int
+}
+
+var lootTables = map[int]lootTable{
+	MANNEQUIN: {
+		entries: []lootEntry{
+			{item: HeartItem, weight: 3, minQuantity: 1, maxQuantity: 1},
+			{item: StoneItem, weight: 1, minQuantity: 1, maxQuantity: 2},
+		},
+		nothingWeight: 1,
+		rolls:         1,
+	},
+	KING: {
+		guaranteed: []lootEntry{
+			{item: HeartContainerItem, minQuantity: 1, maxQuantity: 1},
+		},
+		entries: []lootEntry{
+			{item: HeartItem, weight: 1, minQuantity: 1, maxQuantity: 2},
+			{item: StoneItem, weight: 2, minQuantity: 1, maxQuantity: 3},
+		},
+		nothingWeight: 2,
+		rolls:         2,
+	},
+	LEPRECHAUN: {
+		entries: []lootEntry{
+			{item: HeartItem, weight: 2, minQuantity: 1, maxQuantity: 1},
+			{item: StoneItem, weight: 3, minQuantity: 2, maxQuantity: 4},
+			{item: HerbItem, weight: 1, minQuantity: 1, maxQuantity: 1},
+		},
+		nothingWeight: 1,
+		rolls:         2,
+	},
+}
+
+// roll returns the items dropped by a single kill
+func (table *lootTable) roll(rng *rand.Rand) []item {
+	drops := make([]item, 0)
+	for i := range table.guaranteed {
+		drops = append(drops, table.guaranteed[i].rollQuantity(rng)...)
+	}
+
+	totalWeight := table.nothingWeight
+	for _, entry := range table.entries {
+		totalWeight += entry.weight
+	}
+	if totalWeight <= 0 {
+		return drops
+	}
+
+	for roll := 0; roll < table.rolls; roll++ {
+		pick := rng.IntN(totalWeight)
+		if pick < table.nothingWeight {
+			continue
+		}
+		pick -= table.nothingWeight
+		for i := range table.entries {
+			if pick < table.entries[i].weight {
+				drops = append(drops, table.entries[i].rollQuantity(rng)...)
+				break
+			}
+			pick -= table.entries[i].weight
+		}
+	}
+	return drops
+}
+
+func (entry *lootEntry) rollQuantity(rng *rand.Rand) []item {
+	quantity := entry.minQuantity
+	if entry.maxQuantity > entry.minQuantity {
+		quantity += rng.IntN(entry.maxQuantity - entry.minQuantity + 1)
+	}
+	drops := make([]item, 0, quantity)
+	for i := 0; i < quantity; i++ {
+		drops = append(drops, entry.item)
+	}
+	return drops
+}
+
+// findDropTiles searches outward from the tile under (x, y) for up to count walkable tiles on level that
+// do not already hold a dropped item. Tiles are returned in grid coordinates, closest first.
+func (game *rpgGame) findDropTiles(level *tiled.Map, x, y, count int) []image.Point {
+	levelIndex := game.levelIndex(level)
+	if levelIndex < 0 || count <= 0 {
+		return nil
+	}
+	grid := game.pathGrids[levelIndex]
+	tileWidth := level.TileWidth * worldScale
+	tileHeight := level.TileHeight * worldScale
+
+	occupied := make([]image.Point, 0)
+	for _, dropped := range game.droppedItems {
+		if dropped.level == level {
+			occupied = append(occupied, image.Pt(dropped.xLoc/tileWidth, dropped.yLoc/tileHeight))
+		}
+	}
+
+	start := image.Pt(x/tileWidth, y/tileHeight)
+	if grid.Get(start.X, start.Y) == nil {
+		return nil
+	}
+	found := make([]image.Point, 0, count)
+	visited := map[image.Point]bool{start: true}
+	queue := []image.Point{start}
+	for len(queue) > 0 && len(found) < count {
+		tile := queue[0]
+		queue = queue[1:]
+
+		if grid.Get(tile.X, tile.Y).Walkable && !slices.Contains(occupied, tile) {
+			found = append(found, tile)
+		}
+		for _, step := range []image.Point{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+			next := tile.Add(step)
+			if !visited[next] && grid.Get(next.X, next.Y) != nil {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return found
+}
+
+// scatterItems drops items onto distinct walkable tiles around (x, y) on level, piling any that don't fit at (x, y)
+func (game *rpgGame) scatterItems(items []item, level *tiled.Map, x, y int) {
+	tiles := game.findDropTiles(level, x, y, len(items))
+	for i, droppedItem := range items {
+		droppedItem.level = level
+		if i < len(tiles) {
+			tileWidth := level.TileWidth * worldScale
+			tileHeight := level.TileHeight * worldScale
+			droppedItem.xLoc = tiles[i].X*tileWidth + (tileWidth-droppedItem.picture.Bounds().Dx()*resizeScale)/2
+			droppedItem.yLoc = tiles[i].Y*tileHeight + (tileHeight-droppedItem.picture.Bounds().Dy()*resizeScale)/2
+		} else {
+			//nowhere left to scatter to
+			droppedItem.xLoc = x
+			droppedItem.yLoc = y
+		}
+		game.droppedItems = append(game.droppedItems, droppedItem)
+	}
+}
@@ -2,11 +2,11 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"github.com/co0p/tankism/lib/collision"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/wav"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
 	"github.com/hajimehoshi/ebiten/v2/text"
@@ -15,10 +15,11 @@ import (
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 	"image"
-	"log"
+	"image/color"
+	"io/fs"
 	"math"
+	"os"
 	"path"
-	"slices"
 	"strconv"
 )
 
@@ -28,9 +29,8 @@ var EmbeddedAssets embed.FS
 const (
 	resizeScale     = 3
 	worldScale      = 3
-	COOLDOWN        = 60
+	TICKSPERSECOND  = 60
 	soundSampleRate = 48000
-	LINEOFSITERANGE = 350
 )
 
 const (
@@ -59,117 +59,132 @@ const (
 	RETURNEDITEM
 )
 
+// tile IDs that block movement, or take the player to another map, on any layer
+var (
+	barrierTileIDs    = []uint32{40, 41, 42, 43, 80, 81, 82, 83}
+	teleporterTileIDs = []uint32{1, 2, 3}
+)
+
+// teleporterDestinations is the index in levelMaps each teleporter tile leads to
+var teleporterDestinations = map[uint32]int{
+	1: 1,
+	2: 2,
+	3: 0,
+}
+
 type rpgGame struct {
 	worldinfo
 
-	barrierRect     []image.Rectangle
-	teleporterRects map[uint32]image.Rectangle
-	windowWidth     int
-	windowHeight    int
-	barrierIDs      []uint32
-	player          player
-	enemies         []character
-	questGiver      character
-	fontLarge       font.Face
-	fontSmall       font.Face
-	heartImage      image.Image
-	droppedItems    []item
-	sounds          sounds
-}
-
-type sounds struct {
-	enemyDeath     sound
-	enemyHit       sound
-	attackPowerUp  sound
-	heal           sound
-	playerInteract sound
-	playerDamaged  sound
-	questGiverTalk sound
-	itemPickup     sound
-
-	audioContext *audio.Context
-}
-
-type sound struct {
-	audioPlayer *audio.Player
-}
-
-func (sound *sound) playSound() {
-	//sound.
-	err := sound.audioPlayer.Rewind()
-	if err != nil {
-		fmt.Println("Error rewinding sound: ", err)
-	}
-	sound.audioPlayer.Play()
+	logicalWidth  int
+	logicalHeight int
+	canvas        *ebiten.Image
+	view          viewport
+	debugOverlay  bool
+	console       *developerConsole
+	godMode       bool
+	noclip        bool
+	watcher       *assetWatcher
+	missingTiles  []image.Rectangle
+	player        player
+	enemies       []character
+	questGiver    character
+	fontLarge     font.Face
+	fontSmall     font.Face
+	droppedItems  []item
+	levelCurve    []levelGrowth
+	damageNumbers []damageNumber
+	projectiles   []projectile
+	random        *gameRandom
+	sounds        *soundManager
+
+	scenes            []scene
+	inProgress        bool
+	respawn           respawnPoint
+	settings          settings
+	start             launchStart
+	input             inputState
+	ticks             int
+	headless          bool
+	recorder          *inputRecorder
+	replay            *inputReplay
+	dialogueTicks     int
+	playerSpriteSheet *ebiten.Image
+	enemySpriteSheet  *ebiten.Image
 }
 
-func loadEmbeddedWavToSound(name string, context *audio.Context) sound {
-	file, err := EmbeddedAssets.Open(path.Join("assets", "sounds", name))
-	if err != nil {
-		fmt.Println("Error Loading embedded sound: ", err)
-	}
-	soundWav, err := wav.DecodeWithoutResampling(file)
-	if err != nil {
-		fmt.Println("Error interpreting sound file: ", err)
-	}
-	soundPlay, err := context.NewPlayer(soundWav)
-	if err != nil {
-		fmt.Println("Couldn't create sound player: ", err)
-	}
-	return sound{soundPlay}
+// Update runs only the scene on top of the stack, so anything underneath is frozen. Input for the tick is read once
+// up front, from a replay if one is playing.
+func (game *rpgGame) Update() error {
+	frame := game.nextInputFrame()
+	game.input.advance(frame)
+	if game.keyJustPressed(game.settings.keys[KEYMUTE]) {
+		game.sounds.toggleMute()
+	}
+	if game.keyJustPressed(game.settings.keys[KEYFULLSCREEN]) {
+		game.toggleFullscreen()
+	}
+	if game.keyJustPressed(DEBUGTOGGLEKEY) {
+		game.debugOverlay = !game.debugOverlay
+	}
+	game.sounds.update()
+	game.hotReload()
+	err := game.scenes[len(game.scenes)-1].update(game)
+	game.endTick(frame)
+	return err
 }
 
-func (game *rpgGame) Update() error {
+// updateWorld advances the game world by one tick, it is driven by gameplayScene
+func (game *rpgGame) updateWorld() {
 	getPlayerInput(game)
 
 	game.player.animatePlayerSprite()
 	game.animateDroppedItems()
-	if game.player.action != DEAD {
-		if ebiten.IsKeyPressed(ebiten.KeySpace) {
+	game.animateCheckpoints()
+	game.updateCombatEffects()
+	game.updateAllStatusEffects()
+	game.dialogueTicks++
+	if game.player.action != DEAD && !game.player.isKnockedBack() && !game.player.isStunned() {
+		if game.isKeyPressed(KEYATTACK) {
 			game.player.action = INTERACT
 		} else {
 			game.player.action = WALK
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyA) && game.player.action == WALK {
+		if game.isKeyPressed(KEYLEFT) && game.player.action == WALK {
 			game.movePlayer(&game.player.xLoc)
-		} else if ebiten.IsKeyPressed(ebiten.KeyD) && game.player.action == WALK {
+		} else if game.isKeyPressed(KEYRIGHT) && game.player.action == WALK {
 			game.movePlayer(&game.player.xLoc)
-		} else if ebiten.IsKeyPressed(ebiten.KeyW) && game.player.action == WALK {
+		} else if game.isKeyPressed(KEYUP) && game.player.action == WALK {
 			game.movePlayer(&game.player.yLoc)
-		} else if ebiten.IsKeyPressed(ebiten.KeyS) && game.player.action == WALK {
+		} else if game.isKeyPressed(KEYDOWN) && game.player.action == WALK {
 			game.movePlayer(&game.player.yLoc)
 		}
 	}
 	game.outOfBoundsCheck()
-	//fmt.Printf("x: %d, y: %d\n", game.player.xLoc, game.player.yLoc)
+	teleID := getTeleporterCollisionID(game.teleporterRects, &game.player)
+	if teleID != 0 {
+		game.changeWorldMap(teleID)
+	}
 	game.itemsPickupCheck()
 	if game.player.convertHeartItemsToHealth() {
-		game.sounds.heal.playSound()
+		game.sounds.play("heal")
 	}
 
-	if game.player.action == INTERACT && game.player.interactCooldown < 0 {
-		game.sounds.playerInteract.playSound()
-		game.player.interactCooldown = COOLDOWN
-		for i := range game.enemies {
-			if game.enemies[i].level == game.levelCurrent {
-				if game.player.playerInteractWithCharacterCheck(&game.enemies[i]) {
-					game.enemies[i].hitPoints -= game.player.attackPower
-					game.sounds.enemyHit.playSound()
-					if game.enemies[i].hitPoints == 0 {
-						game.enemies[i].death(game)
-					}
-				}
-			}
-		}
+	if game.player.action == INTERACT && game.player.interactCooldown < 0 && !game.player.isStunned() {
+		game.sounds.play("playerInteract")
+		game.player.interactCooldown = game.settings.AttackCooldown
+		game.player.startAttack()
+		game.checkpointInteractCheck()
 		if game.player.playerInteractWithCharacterCheck(&game.questGiver) && game.questGiver.level == game.levelCurrent {
 			if game.player.questProgress == NOTTALKED {
 				game.player.questProgress = TALKED
+				game.dialogueTicks = 0
 				//display quest text
-				game.sounds.questGiverTalk.playSound()
+				game.sounds.play("questGiverTalk")
 			} else if game.player.questProgress == TALKED && game.player.questCheckInventoryForBook() { //AND H IASTEM
 				game.player.questProgress = RETURNEDITEM
+				game.dialogueTicks = 0
 				game.player.attackPower++
-				game.sounds.attackPowerUp.playSound()
+				game.sounds.play("attackPowerUp")
 			} else if game.player.questProgress == RETURNEDITEM {
 				//display another thank you message?
 			}
@@ -177,25 +192,28 @@ func (game *rpgGame) Update() error {
 	} else if game.player.interactCooldown > -10 {
 		game.player.interactCooldown--
 	}
+	game.playerAttackCheck()
+	game.playerRangedAttack()
 
 	for i := range game.enemies {
 		if game.enemies[i].action == PATH && game.enemies[i].pathUpdateCooldown < 0 &&
 			game.enemies[i].level == game.levelCurrent {
 
-			game.enemies[i].pathUpdateCooldown = COOLDOWN
+			game.enemies[i].pathUpdateCooldown = TICKSPERSECOND
 			game.updatePath(&game.enemies[i], &game.player)
 
 		} else if game.enemies[i].pathUpdateCooldown > -10 {
 			game.enemies[i].pathUpdateCooldown--
 		}
 
+		sightRange := game.sightRange()
 		playerX := game.player.xLoc + game.player.FRAME_WIDTH
-		xMin := game.enemies[i].xLoc + (game.enemies[i].FRAME_WIDTH / 2) - LINEOFSITERANGE
-		xMax := game.enemies[i].xLoc + (game.enemies[i].FRAME_WIDTH / 2) + LINEOFSITERANGE
+		xMin := game.enemies[i].xLoc + (game.enemies[i].FRAME_WIDTH / 2) - sightRange
+		xMax := game.enemies[i].xLoc + (game.enemies[i].FRAME_WIDTH / 2) + sightRange
 
 		playerY := game.player.yLoc + game.player.FRAME_HEIGHT
-		yMin := game.enemies[i].yLoc + (game.enemies[i].FRAME_HEIGHT / 2) - LINEOFSITERANGE
-		yMax := game.enemies[i].yLoc + (game.enemies[i].FRAME_HEIGHT / 2) + LINEOFSITERANGE
+		yMin := game.enemies[i].yLoc + (game.enemies[i].FRAME_HEIGHT / 2) - sightRange
+		yMax := game.enemies[i].yLoc + (game.enemies[i].FRAME_HEIGHT / 2) + sightRange
 		if game.enemies[i].action != DEAD {
 			if (xMin < playerX && playerX < xMax) && (yMin < playerY && playerY < yMax) {
 				game.enemies[i].action = PATH
@@ -210,6 +228,7 @@ func (game *rpgGame) Update() error {
 		game.player.action = DEAD
 	} else {
 		game.enemiesAttack()
+		game.enemiesRangedAttack()
 		game.enemiesPathing()
 		for i := range game.enemies {
 			game.enemies[i].animateCharacter()
@@ -217,39 +236,54 @@ func (game *rpgGame) Update() error {
 	}
 
 	game.questGiver.animateCharacter()
-
-	return nil
+	game.updateProjectiles()
 }
 
 func (game *rpgGame) movePlayer(location *int) {
-	if isBorderColliding(game.barrierRect, &game.player) {
-		*location -= game.player.translateDirectionToPositiveNegative() * game.player.speed * 5
+	if !game.noclip && isBorderColliding(game.barrierRect, &game.player.character) {
+		*location -= game.player.translateDirectionToPositiveNegative() * game.player.effectiveSpeed() * 5
 	} else if game.player.action != DEAD {
-		*location += game.player.translateDirectionToPositiveNegative() * game.player.speed
+		*location += game.player.translateDirectionToPositiveNegative() * game.player.effectiveSpeed()
 	}
 }
 
 func (game *rpgGame) outOfBoundsCheck() {
 	if game.player.xLoc < -100 || game.player.xLoc > game.levelCurrent.TileWidth*game.levelCurrent.Width*worldScale {
-		game.player.xLoc = 300
-		game.player.yLoc = 300
+		game.moveToRespawnPoint()
 	} else if game.player.yLoc < -100 || game.player.yLoc > game.levelCurrent.TileHeight*game.levelCurrent.Height*worldScale {
-		game.player.xLoc = 300
-		game.player.yLoc = 300
+		game.moveToRespawnPoint()
 	}
 }
 
+// Draw draws every scene in the stack from the bottom up, so menus are drawn over the world. Scenes draw onto the
+// logical canvas, which is then scaled into the window.
 func (game *rpgGame) Draw(screen *ebiten.Image) {
+	game.canvas.Clear()
+	for _, scene := range game.scenes {
+		scene.draw(game, game.canvas)
+	}
+	game.drawLetterboxed(screen)
+	if _, inWorld := game.scenes[0].(*gameplayScene); inWorld && game.debugOverlay {
+		game.drawDebugOverlay(screen)
+	}
+	for _, scene := range game.scenes {
+		if overlay, ok := scene.(overlayScene); ok {
+			overlay.drawOverlay(game, screen)
+		}
+	}
+}
+
+func (game *rpgGame) drawWorld(screen *ebiten.Image) {
 	//screen.Fill(colornames.Blue)
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Reset()
 
-	var teleporterIDs = []uint32{1, 2, 3}
+	game.missingTiles = game.missingTiles[:0]
 	for _, layer := range game.levelCurrent.Layers {
 		for tileY := 0; tileY < game.levelCurrent.Height; tileY++ {
 			for tileX := 0; tileX < game.levelCurrent.Width; tileX++ {
 				op.GeoM.Reset()
-				//get on screen position
+				//get on screen position, the logical screen is in map pixels
 				tileXPos := float64(game.levelCurrent.TileWidth * tileX)
 				tileYPos := float64(game.levelCurrent.TileHeight * tileY)
 				op.GeoM.Translate(tileXPos, tileYPos)
@@ -257,26 +291,17 @@ func (game *rpgGame) Draw(screen *ebiten.Image) {
 				// Get the tile ID from the appropriate LAYER
 				tileToDraw := layer.Tiles[tileY*game.levelCurrent.Width+tileX]
 
-				if slices.Contains(game.barrierIDs, tileToDraw.ID) {
-					barrierRectangle := image.Rect(int(tileXPos), int(tileYPos),
-						int(tileXPos)+game.levelCurrent.TileWidth, int(tileYPos)+game.levelCurrent.TileHeight)
-					game.barrierRect = append(game.barrierRect, barrierRectangle)
-				}
-				if slices.Contains(teleporterIDs, tileToDraw.ID) {
-					teleporterRectangle := image.Rect(int(tileXPos), int(tileYPos),
-						int(tileXPos)+game.levelCurrent.TileWidth, int(tileYPos)+game.levelCurrent.TileHeight)
-					game.teleporterRects[tileToDraw.ID] = teleporterRectangle
-				}
-
 				if tileToDraw.ID != 0 {
 					// Retrieve the corresponding sub-image from the map
 					ebitenTileToDraw, ok := game.tileHashCurrent[tileToDraw.ID]
 					if !ok {
-						// Handle the case where the tile ID is not found in the map
-						fmt.Printf("Tile ID %d not found in tileHashCurrent\n", tileToDraw.ID)
+						// Handle the case where the tile ID is not found in the map, the debug overlay marks these
+						warnRepeated(worldLog, "missing tile "+strconv.Itoa(int(tileToDraw.ID)), "tile has no image",
+							"tile", tileToDraw.ID, "map", game.levelIndex(game.levelCurrent))
+						game.missingTiles = append(game.missingTiles, image.Rect(int(tileXPos), int(tileYPos),
+							int(tileXPos)+game.levelCurrent.TileWidth, int(tileYPos)+game.levelCurrent.TileHeight))
 						continue
 					}
-					op.GeoM.Scale(worldScale, worldScale)
 					// Draw the sub-image
 					screen.DrawImage(ebitenTileToDraw, op)
 				}
@@ -284,14 +309,12 @@ func (game *rpgGame) Draw(screen *ebiten.Image) {
 		}
 	}
 
-	teleID := getTeleporterCollisionID(game.teleporterRects, &game.player)
-	if teleID != 0 {
-		game.changeWorldMap(teleID)
+	game.drawCheckpoints(op, screen)
+	if !game.player.isFlashing() {
+		drawPlayerFromSpriteSheet(op, screen, game.player)
 	}
-
-	drawPlayerFromSpriteSheet(op, screen, game.player)
 	for _, charact := range game.enemies {
-		if charact.level == game.levelCurrent {
+		if charact.level == game.levelCurrent && !charact.isFlashing() {
 			drawImageFromSpriteSheet(op, screen, charact)
 		}
 	}
@@ -302,38 +325,50 @@ func (game *rpgGame) Draw(screen *ebiten.Image) {
 	for _, item := range game.droppedItems {
 		if item.level == game.levelCurrent {
 			op.GeoM.Reset()
-			op.GeoM.Scale(resizeScale-1, resizeScale-1)
+			op.GeoM.Scale(resizeScale, resizeScale)
 			op.GeoM.Translate(float64(item.xLoc), float64(item.yLoc-item.yAnimationOffset))
-			screen.DrawImage(item.picture.(*ebiten.Image), op)
+			drawWorldImage(screen, item.picture.(*ebiten.Image), op)
 		}
 	}
 
+	game.drawProjectiles(op, screen)
+	game.drawLevelUpEffect(op, screen)
+	game.drawDamageNumbers(screen)
 	game.drawPlayerHealth(op, screen)
+	game.drawStatusIcons(op, screen)
 	if game.questGiver.level == game.levelCurrent {
 		switch game.player.questProgress {
 		case TALKED:
-			DrawCenteredText(screen, game.fontSmall, "My brother stole my book,\n   please get it back!",
+			game.drawSpeech(screen,
+				game.revealedText("My brother stole my book,\n   please get it back!", game.dialogueTicks),
 				game.questGiver.xLoc+45, game.questGiver.yLoc)
 
 		case RETURNEDITEM:
-			DrawCenteredText(screen, game.fontSmall, "  Thank You!\nI've blessed you\n  with strength",
+			game.drawSpeech(screen,
+				game.revealedText("  Thank You!\nI've blessed you\n  with strength", game.dialogueTicks),
 				game.questGiver.xLoc+45, game.questGiver.yLoc)
 		}
 	}
 
-	DrawCenteredText(screen, game.fontSmall, "Power:", 50, 700)
-	DrawCenteredText(screen, game.fontSmall, strconv.Itoa(game.player.attackPower), 120, 700)
+	hudY := game.logicalHeight - HUDMARGIN
+	DrawCenteredText(screen, game.fontSmall, "Power:", 28, hudY)
+	DrawCenteredText(screen, game.fontSmall, strconv.Itoa(game.player.attackPower), 60, hudY)
+	game.drawExperienceBar(screen)
+}
 
-	if game.player.action == DEAD {
-		DrawCenteredText(screen, game.fontLarge, "GAME OVER", game.windowHeight/2, game.windowWidth/2)
-	}
+// drawSpeech draws what a character says centred on (x, y) in world pixels, moved along just enough to stay on
+// the screen
+func (game *rpgGame) drawSpeech(screen *ebiten.Image, speech string, x, y int) {
+	bounds := text.BoundString(game.fontSmall, speech)
+	centerX := min(max(x/worldScale, bounds.Dx()/2), game.logicalWidth-bounds.Dx()/2)
+	DrawCenteredText(screen, game.fontSmall, speech, centerX, y/worldScale)
 }
 
 func drawPlayerFromSpriteSheet(op *ebiten.DrawImageOptions, screen *ebiten.Image, targetCharacter player) {
 	op.GeoM.Reset()
 	op.GeoM.Scale(resizeScale, resizeScale)
 	op.GeoM.Translate(float64(targetCharacter.xLoc), float64(targetCharacter.yLoc))
-	screen.DrawImage(targetCharacter.spriteSheet.SubImage(
+	drawWorldImage(screen, targetCharacter.spriteSheet.SubImage(
 		image.Rect(
 			targetCharacter.frame*targetCharacter.FRAME_WIDTH,
 			targetCharacter.direction*targetCharacter.FRAME_HEIGHT,
@@ -351,7 +386,7 @@ func drawImageFromSpriteSheet(op *ebiten.DrawImageOptions, screen *ebiten.Image,
 		op.GeoM.Translate(
 			float64(targetCharacter.xLoc)+(float64(targetCharacter.FRAME_WIDTH)*resizeScale), float64(targetCharacter.yLoc))
 	}
-	screen.DrawImage(targetCharacter.spriteSheet.SubImage(
+	drawWorldImage(screen, targetCharacter.spriteSheet.SubImage(
 		image.Rect(
 			targetCharacter.frame*targetCharacter.FRAME_WIDTH,
 			targetCharacter.imageYOffset*targetCharacter.FRAME_HEIGHT,
@@ -359,53 +394,189 @@ func drawImageFromSpriteSheet(op *ebiten.DrawImageOptions, screen *ebiten.Image,
 			targetCharacter.FRAME_HEIGHT+targetCharacter.FRAME_HEIGHT*targetCharacter.imageYOffset)).(*ebiten.Image), op)
 }
 
-func (game *rpgGame) drawPlayerHealth(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
-	op.GeoM.Reset()
-	op.GeoM.Scale(worldScale, worldScale)
-	for i := 0; i < game.player.hitPoints; i++ {
-		screen.DrawImage(game.heartImage.(*ebiten.Image), op)
-		op.GeoM.Translate(16*worldScale, 0)
-	}
-}
-
 func (game *rpgGame) animateDroppedItems() {
 	for i := range game.droppedItems {
 		game.droppedItems[i].itemAnimate()
 	}
 }
 
-func (game *rpgGame) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return outsideWidth, outsideHeight //by default, just return the current dimensions
-}
-
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:], os.Stdout))
+		case "render-map":
+			os.Exit(runRenderMap(os.Args[2:], os.Stdout))
+		case "verify-replay":
+			os.Exit(runVerifyReplay(os.Args[2:], os.Stdout))
+		}
+	}
+	logLevel := flag.String("log-level", "info", "lowest level to log: debug, info, warn or error")
+	logFile := flag.String("log-file", "", "append logs to this file instead of stderr")
+	dataDir := flag.String("data-dir", "", "folder laid out like assets whose files replace or add to the game's own")
+	modsDir := flag.String("mods-dir", DEFAULTMODSDIR, "folder of mods, each a folder laid out like assets")
+	dev := flag.Bool("dev", false, "reload maps and data files from the data directory and mods when they change, "+
+		"using ./assets as the data directory if none is given")
+	var launch launchOptions
+	flag.StringVar(&launch.startMap, "map", "", "map a new game starts on, by name or number")
+	flag.StringVar(&launch.spawn, "spawn", "", "x,y on screen where a new game puts the player")
+	flag.IntVar(&launch.windowScale, "window-scale", 0, "window size for this run, overriding the saved setting")
+	flag.Int64Var(&launch.seed, "seed", 0, "seed for all randomness, overriding the settings file, random if 0")
+	flag.BoolVar(&launch.debugOverlay, "debug", false, "start with the debug overlay shown")
+	flag.BoolVar(&launch.skipTitle, "skip-title", false, "start a new game straight away")
+	flag.IntVar(&launch.ticks, "ticks", 0, "run this many updates without a window then exit, for smoke tests")
+	recordFile := flag.String("record", "", "record this session's input to a replay file")
+	replayFile := flag.String("replay", "", "play back a session recorded with -record")
+	flag.Parse()
+	logOutput, err := setupLogging(*logLevel, *logFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer logOutput.Close()
+	if *dev && *dataDir == "" {
+		if info, err := os.Stat(DEVASSETSDIR); err == nil && info.IsDir() {
+			*dataDir = DEVASSETSDIR
+		}
+	}
+	if err := setupAssets(*dataDir, *modsDir); err != nil {
+		gameLog.Error("setting up assets", "err", err)
+		logOutput.Close()
+		os.Exit(2)
+	}
+
 	ebiten.SetWindowTitle("SimpleRPG")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
-	soundContext := audio.NewContext(soundSampleRate)
+	game, problems := newGame()
+	if len(problems) > 0 {
+		//show what is wrong instead of starting a game that would crash part way through
+		for _, problem := range problems {
+			gameLog.Error("startup check failed", "err", problem)
+		}
+		if err := ebiten.RunGame(&errorReport{problems: problems}); err != nil {
+			gameLog.Error("failed to show error report", "err", err)
+		}
+		logOutput.Close()
+		os.Exit(1)
+	}
+	if *dev {
+		game.watcher = newAssetWatcher(watchedDirectories(*dataDir, *modsDir))
+		gameLog.Info("watching assets for changes", "directories", game.watcher.directories)
+	}
+	game.applySettings()
+	if *replayFile != "" {
+		replay, err := loadReplay(*replayFile)
+		if err == nil {
+			err = game.startReplay(replay, launch)
+		}
+		if err != nil {
+			gameLog.Error("starting replay", "err", err)
+			logOutput.Close()
+			os.Exit(2)
+		}
+	} else {
+		if *recordFile != "" {
+			//recordings always start from a new game so replays know where they begin
+			launch.skipTitle = true
+		}
+		if err := game.applyLaunchOptions(launch); err != nil {
+			gameLog.Error("launch options", "err", err)
+			logOutput.Close()
+			os.Exit(2)
+		}
+	}
+	if *recordFile != "" {
+		game.recorder, err = newInputRecorder(*recordFile, game.replayHeader(launch))
+		if err != nil {
+			gameLog.Error("starting recording", "err", err)
+			logOutput.Close()
+			os.Exit(2)
+		}
+		defer game.recorder.Close()
+	}
+	if launch.ticks > 0 {
+		if err := game.runHeadless(launch.ticks); err != nil {
+			gameLog.Error("headless run failed", "err", err)
+			logOutput.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	if err := ebiten.RunGame(game); err != nil {
+		gameLog.Error("failed to run game", "err", err)
+		logOutput.Close()
+		os.Exit(1)
+	}
+}
 
-	sounds := sounds{
-		enemyDeath:     loadEmbeddedWavToSound("enemyDeath.wav", soundContext),
-		enemyHit:       loadEmbeddedWavToSound("enemyHit.wav", soundContext),
-		attackPowerUp:  loadEmbeddedWavToSound("attackPowerUp.wav", soundContext),
-		heal:           loadEmbeddedWavToSound("heal.wav", soundContext),
-		playerInteract: loadEmbeddedWavToSound("playerInteract.wav", soundContext),
-		playerDamaged:  loadEmbeddedWavToSound("playerDamaged.wav", soundContext),
-		questGiverTalk: loadEmbeddedWavToSound("questGiverTalk.wav", soundContext),
-		itemPickup:     loadEmbeddedWavToSound("itemPickup.wav", soundContext),
+// newGame loads every asset the game needs and checks the ones that are only loaded later on, returning all the
+// problems it found rather than stopping at the first
+func newGame() (*rpgGame, []error) {
+	var problems []error
+	//ebiten allows only one audio context, so a second game in the same process shares the first one's
+	context := audio.CurrentContext()
+	if context == nil {
+		context = audio.NewContext(soundSampleRate)
+	}
+	sounds := newSoundManager(context)
+	if err := sounds.loadEmbeddedSounds(); err != nil {
+		problems = append(problems, err)
 	}
 
-	world := initializeWorldInfo()
+	world, err := initializeWorldInfo()
+	if err != nil {
+		return nil, append(problems, err)
+	}
 
-	windowX := world.levelCurrent.TileWidth * world.levelCurrent.Width * worldScale
-	windowY := world.levelCurrent.TileHeight * world.levelCurrent.Height * worldScale
+	windowX := VIEWTILESWIDE * world.levelCurrent.TileWidth
+	windowY := VIEWTILESHIGH * world.levelCurrent.TileHeight
 
 	//windowX := gameMap.TileWidth * gameMap.Width * worldScale
 	//windowY := gameMap.TileHeight * gameMap.Height * worldScale
-	ebiten.SetWindowSize(windowX, windowY)
-	fmt.Printf("windowWidth: %d, windowHeight: %d\n", windowX, windowY)
+	gameLog.Debug("logical screen size", "width", windowX, "height", windowY)
+
+	settings := loadSettings()
+	game := rpgGame{
+		//levelCurrent:    gameMap,
+		//tileHashCurrent: ebitenImageMap,
+		//levelMaps:       levelmaps,
+		//tileHashes:      tileMapHashes,
+		worldinfo:     *world,
+		logicalWidth:  windowX,
+		logicalHeight: windowY,
+		canvas:        ebiten.NewImage(windowX, windowY),
+		random:        newGameRandom(chooseSeed(settings.Seed)),
+		sounds:        sounds,
+		settings:      settings,
+	}
+
+	load := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+	load(reloadObjectsSheet())
+	game.fontLarge, err = LoadScoreFont(16)
+	load(err)
+	game.fontSmall, err = LoadScoreFont(8)
+	load(err)
+	game.levelCurve, err = loadLevelCurve("levels.json")
+	load(err)
+	game.playerSpriteSheet, err = LoadEmbeddedImage("characters", "player.png")
+	load(err)
+	game.enemySpriteSheet, err = LoadEmbeddedImage("characters", "characters.png")
+	load(err)
+	problems = append(problems, game.validateAssets()...)
+	return &game, problems
+}
 
-	playerSpriteSheet := LoadEmbeddedImage("characters", "player.png")
-	enemySpriteSheet := LoadEmbeddedImage("characters", "characters.png")
+// startNewGame puts the player, enemies and items back where a fresh game starts them
+func (game *rpgGame) startNewGame() {
+	//every stream starts over, so each new game with the same seed plays out the same way
+	game.random = newGameRandom(game.random.seed)
+	playerSpriteSheet := game.playerSpriteSheet
+	enemySpriteSheet := game.enemySpriteSheet
 
 	user := player{
 		character: character{
@@ -419,12 +590,15 @@ func main() {
 			FRAME_WIDTH:      16,
 			imageYOffset:     -1,
 			speed:            3,
-			hitPoints:        3,
-			interactCooldown: COOLDOWN / 2,
+			hitPoints:        3 * HEALTHPERHEART,
+			maxHitPoints:     3 * HEALTHPERHEART,
+			interactCooldown: game.settings.AttackCooldown / 2,
 			attackPower:      1,
 			action:           WALK,
 		},
-		questProgress: NOTTALKED,
+		questProgress:   NOTTALKED,
+		weapon:          SwordWeapon,
+		experienceLevel: 1,
 	}
 	questGiver := character{
 		spriteSheet:      enemySpriteSheet,
@@ -438,156 +612,112 @@ func main() {
 		FRAME_WIDTH:      32,
 		action:           WALK,
 		imageYOffset:     0,
-		level:            world.levelMaps[2],
+		level:            game.levelMaps[2],
 		hitPoints:        1,
-		interactCooldown: COOLDOWN,
+		interactCooldown: game.settings.AttackCooldown,
 	}
 
-	mannequinInventory := make([]item, 0)
-	mannequinInventory = append(mannequinInventory, BookItem)
-
-	mannequin := character{
-		spriteSheet:        enemySpriteSheet,
-		xLoc:               500,
-		yLoc:               200,
-		inventory:          mannequinInventory,
-		direction:          CHARACTLEFT,
-		frame:              0,
-		frameDelay:         0,
-		FRAME_HEIGHT:       32,
-		FRAME_WIDTH:        32,
-		action:             STAY,
-		imageYOffset:       0,
-		speed:              1,
-		level:              world.levelMaps[1],
-		hitPoints:          2,
-		interactCooldown:   COOLDOWN,
-		attackPower:        1,
-		pathUpdateCooldown: COOLDOWN,
-	}
-
-	king := character{
-		spriteSheet:        enemySpriteSheet,
-		xLoc:               100,
-		yLoc:               100,
-		inventory:          nil,
-		direction:          CHARACTLEFT,
-		frame:              0,
-		frameDelay:         0,
-		FRAME_HEIGHT:       32,
-		FRAME_WIDTH:        32,
-		action:             STAY,
-		imageYOffset:       1,
-		speed:              1,
-		level:              world.levelMaps[0],
-		hitPoints:          2,
-		interactCooldown:   COOLDOWN,
-		attackPower:        1,
-		pathUpdateCooldown: COOLDOWN,
-	}
-
-	leprechaun := character{
-		spriteSheet:        enemySpriteSheet,
-		xLoc:               300,
-		yLoc:               300,
-		inventory:          nil,
-		direction:          CHARACTRIGHT,
-		frame:              0,
-		frameDelay:         0,
-		FRAME_HEIGHT:       32,
-		FRAME_WIDTH:        32,
-		action:             STAY,
-		imageYOffset:       2,
-		speed:              1,
-		level:              world.levelMaps[0],
-		hitPoints:          2,
-		interactCooldown:   COOLDOWN,
-		attackPower:        1,
-		pathUpdateCooldown: COOLDOWN,
-	}
+	mannequin := game.newEnemy(MANNEQUIN, game.levelMaps[1], 500, 200)
+	king := game.newEnemy(KING, game.levelMaps[0], 100, 100)
+	leprechaun := game.newEnemy(LEPRECHAUN, game.levelMaps[0], 300, 300)
+	leprechaun.direction = CHARACTRIGHT
 	enemies := make([]character, 0, 5)
 	enemies = append(enemies, mannequin)
 	enemies = append(enemies, king)
 	enemies = append(enemies, leprechaun)
 
-	heartImage := grabItemImage(63, 0, 16, 16)
 	droppedItems := make([]item, 0, 10)
 	heart := HeartItem
-	heart.level = world.levelMaps[2]
+	heart.level = game.levelMaps[2]
 	droppedItems = append(droppedItems, heart)
 	stone := StoneItem
-	stone.level = world.levelMaps[2]
+	stone.level = game.levelMaps[2]
 	droppedItems = append(droppedItems, stone)
-	fmt.Printf("items: %d\n", droppedItems)
 
-	teleporterRectangles := map[uint32]image.Rectangle{}
+	game.setLevel(2)
+	game.player = user
+	game.questGiver = questGiver
+	game.enemies = enemies
+	game.droppedItems = droppedItems
+	game.projectiles = nil
+	game.damageNumbers = nil
+	game.respawn = respawnPoint{levelIndex: 2, xLoc: user.xLoc, yLoc: user.yLoc}
+	game.moveToLaunchStart()
+	game.inProgress = true
+}
 
-	var barrierID = []uint32{40, 41, 42, 43, 80, 81, 82, 83}
+// enemySpriteRows is the row of the enemy sprite sheet each character type is drawn from
+var enemySpriteRows = map[int]int{
+	MANNEQUIN:  0,
+	KING:       1,
+	LEPRECHAUN: 2,
+}
 
-	game := rpgGame{
-		//levelCurrent:    gameMap,
-		//tileHashCurrent: ebitenImageMap,
-		//levelMaps:       levelmaps,
-		//tileHashes:      tileMapHashes,
-		worldinfo:       *world,
-		player:          user,
-		enemies:         enemies,
-		barrierIDs:      barrierID,
-		windowWidth:     windowX,
-		windowHeight:    windowY,
-		teleporterRects: teleporterRectangles,
-		heartImage:      heartImage,
-		fontLarge:       LoadScoreFont(60),
-		fontSmall:       LoadScoreFont(16),
-		droppedItems:    droppedItems,
-		questGiver:      questGiver,
-		sounds:          sounds,
-	}
-	err := ebiten.RunGame(&game)
-	if err != nil {
-		fmt.Println("Failed to run game", err)
+// newEnemy makes a fresh enemy of characterType standing at (x, y) on level. Mannequins carry the quest's book.
+func (game *rpgGame) newEnemy(characterType int, level *tiled.Map, x int, y int) character {
+	var inventory []item
+	if characterType == MANNEQUIN {
+		inventory = append(inventory, BookItem)
+	}
+	return character{
+		spriteSheet:        game.enemySpriteSheet,
+		xLoc:               x,
+		yLoc:               y,
+		inventory:          inventory,
+		direction:          CHARACTLEFT,
+		frame:              0,
+		frameDelay:         0,
+		FRAME_HEIGHT:       32,
+		FRAME_WIDTH:        32,
+		action:             STAY,
+		imageYOffset:       enemySpriteRows[characterType],
+		speed:              1,
+		level:              level,
+		hitPoints:          2,
+		maxHitPoints:       2,
+		interactCooldown:   game.settings.AttackCooldown,
+		attackPower:        HEALTHPERHEART,
+		pathUpdateCooldown: TICKSPERSECOND,
+		characterType:      characterType,
 	}
 }
 
-func LoadEmbeddedImage(folderName string, imageName string) *ebiten.Image {
-	embeddedFile, err := EmbeddedAssets.Open(path.Join("assets", folderName, imageName))
+func LoadEmbeddedImage(folderName string, imageName string) (*ebiten.Image, error) {
+	imagePath := path.Join("assets", folderName, imageName)
+	embeddedFile, err := GameAssets.Open(imagePath)
 	if err != nil {
-		log.Fatal("failed to load embedded image ", imageName, err)
+		return nil, fmt.Errorf("loading image %s: %w", imagePath, err)
 	}
+	defer embeddedFile.Close()
 	ebitenImage, _, err := ebitenutil.NewImageFromReader(embeddedFile)
 	if err != nil {
-		fmt.Println("Error loading tile image:", imageName, err)
+		return nil, fmt.Errorf("decoding image %s: %w", imagePath, err)
 	}
-	return ebitenImage
+	return ebitenImage, nil
 }
 
-func loadMapFromEmbedded(name string) *tiled.Map {
-	embeddedMap, err := tiled.LoadFile(name, tiled.WithFileSystem(EmbeddedAssets))
+func loadMapFromEmbedded(name string) (*tiled.Map, error) {
+	embeddedMap, err := tiled.LoadFile(name, tiled.WithFileSystem(GameAssets))
 	if err != nil {
-		fmt.Println("Error loading embedded map:", err)
+		return nil, fmt.Errorf("loading map %s: %w", name, err)
 	}
-	return embeddedMap
+	return embeddedMap, nil
 }
 
-func makeEbitenImagesFromMap(tiledMap tiled.Map) map[uint32]*ebiten.Image {
+func makeEbitenImagesFromMap(tiledMap tiled.Map) (map[uint32]*ebiten.Image, error) {
 	idToImage := make(map[uint32]*ebiten.Image)
-	tilesetImagePath := path.Join("assets", tiledMap.Tilesets[0].Image.Source)
-	embeddedFile, err := EmbeddedAssets.Open(tilesetImagePath)
-	if err != nil {
-		log.Fatal("failed to load embedded image ", tilesetImagePath, err)
+	if len(tiledMap.Tilesets) == 0 || tiledMap.Tilesets[0].Image == nil || tiledMap.Tilesets[0].Columns <= 0 {
+		return nil, fmt.Errorf("map has no tileset image")
 	}
-	ebitenImageTileset, _, err := ebitenutil.NewImageFromReader(embeddedFile)
+	ebitenImageTileset, err := LoadEmbeddedImage("", tiledMap.Tilesets[0].Image.Source)
 	if err != nil {
-		fmt.Println("Error loading tileset image:", tilesetImagePath, err)
+		return nil, fmt.Errorf("loading tileset: %w", err)
 	}
 	for _, layer := range tiledMap.Layers {
 		for _, tile := range layer.Tiles {
 
 			if _, ok := idToImage[tile.ID]; !ok { //if tileID does not exists
-				x := int((tile.ID)%uint32(tiledMap.Tilesets[0].Columns)) * tiledMap.TileWidth
-				y := int((tile.ID)/uint32(tiledMap.Tilesets[0].Columns)) * tiledMap.TileHeight
-				subImageRect := image.Rect(x, y, x+tiledMap.TileWidth, y+tiledMap.TileHeight)
-				subImage := ebitenImageTileset.SubImage(subImageRect).(*ebiten.Image)
+				subImage := ebitenImageTileset.SubImage(tileSourceRect(&tiledMap, tile.ID)).(*ebiten.Image)
 				idToImage[tile.ID] = subImage
 			} else {
 				//do nothing?
@@ -595,34 +725,72 @@ func makeEbitenImagesFromMap(tiledMap tiled.Map) map[uint32]*ebiten.Image {
 		}
 	}
 
-	return idToImage
+	return idToImage, nil
+}
+
+// tileSourceRect is where tile id is in the image of tiledMap's first tileset
+func tileSourceRect(tiledMap *tiled.Map, id uint32) image.Rectangle {
+	x := int(id%uint32(tiledMap.Tilesets[0].Columns)) * tiledMap.TileWidth
+	y := int(id/uint32(tiledMap.Tilesets[0].Columns)) * tiledMap.TileHeight
+	return image.Rect(x, y, x+tiledMap.TileWidth, y+tiledMap.TileHeight)
 }
 
+// objectsSheet is objects.png, loaded the first time an item image is grabbed from it
+var objectsSheet *ebiten.Image
+
+// grabItemImage cuts a picture out of objects.png. Item pictures are grabbed while the package is initialised, so if
+// the sheet can't be loaded the error is kept for the startup report and a placeholder is returned instead.
 func grabItemImage(startX, startY, width, height int) image.Image {
-	spriteSheet := LoadEmbeddedImage("", "objects.png")
+	if objectsSheet == nil {
+		spriteSheet, err := LoadEmbeddedImage("", "objects.png")
+		if err != nil {
+			spriteSheetError = err
+			return missingImage(width, height)
+		}
+		objectsSheet = spriteSheet
+	}
 	subImageRect := image.Rect(startX, startY, startX+width, startY+height)
 
-	subImage := ebiten.NewImageFromImage(spriteSheet).SubImage(subImageRect)
+	subImage := objectsSheet.SubImage(subImageRect)
 	return subImage
 }
 
+// reloadObjectsSheet redraws objects.png from GameAssets over the sheet that was loaded while the package was
+// initialised, before any mods were set up. Every item picture is a sub image of it, so they all pick up the change.
+func reloadObjectsSheet() error {
+	if objectsSheet == nil || GameAssets == fs.FS(EmbeddedAssets) {
+		return nil
+	}
+	spriteSheet, err := LoadEmbeddedImage("", "objects.png")
+	if err != nil {
+		return err
+	}
+	if spriteSheet.Bounds() != objectsSheet.Bounds() {
+		worldLog.Warn("objects.png is a different size to the original, sprites may be cut off",
+			"size", spriteSheet.Bounds().Size(), "expected", objectsSheet.Bounds().Size())
+	}
+	objectsSheet.Clear()
+	objectsSheet.DrawImage(spriteSheet, nil)
+	return nil
+}
+
 func getPlayerInput(game *rpgGame) {
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
+	if game.isKeyPressed(KEYLEFT) {
 		game.player.direction = LEFT
-	} else if ebiten.IsKeyPressed(ebiten.KeyD) {
+	} else if game.isKeyPressed(KEYRIGHT) {
 		game.player.direction = RIGHT
-	} else if ebiten.IsKeyPressed(ebiten.KeyW) {
+	} else if game.isKeyPressed(KEYUP) {
 		game.player.direction = UP
-	} else if ebiten.IsKeyPressed(ebiten.KeyS) {
+	} else if game.isKeyPressed(KEYDOWN) {
 		game.player.direction = DOWN
 	}
 }
 
-func LoadScoreFont(size float64) font.Face {
+func LoadScoreFont(size float64) (font.Face, error) {
 	//originally inspired by https://www.fatoldyeti.com/posts/roguelike16/
 	trueTypeFont, err := opentype.Parse(fonts.PressStart2P_ttf)
 	if err != nil {
-		fmt.Println("Error loading font for score:", err)
+		return nil, fmt.Errorf("parsing font: %w", err)
 	}
 	fontFace, err := opentype.NewFace(trueTypeFont, &opentype.FaceOptions{
 		Size:    size,
@@ -630,19 +798,23 @@ func LoadScoreFont(size float64) font.Face {
 		Hinting: font.HintingFull,
 	})
 	if err != nil {
-		fmt.Println("Error loading font of correct size for score:", err)
+		return nil, fmt.Errorf("creating font face of size %v: %w", size, err)
 	}
-	return fontFace
+	return fontFace, nil
 }
 
 func DrawCenteredText(screen *ebiten.Image, font font.Face, s string, cx, cy int) { //from https://github.com/sedyh/ebitengine-cheatsheet
+	DrawCenteredTextColor(screen, font, s, cx, cy, colornames.White)
+}
+
+func DrawCenteredTextColor(screen *ebiten.Image, font font.Face, s string, cx, cy int, textColor color.Color) {
 	bounds := text.BoundString(font, s)
 	x, y := cx-bounds.Min.X-bounds.Dx()/2, cy-bounds.Min.Y-bounds.Dy()/2
-	text.Draw(screen, s, font, x, y, colornames.White)
+	text.Draw(screen, s, font, x, y, textColor)
 }
 
-func isBorderColliding(borderRects []image.Rectangle, player *player) bool {
-	playerBounds := player.getCollisionBoundingBox()
+func isBorderColliding(borderRects []image.Rectangle, character *character) bool {
+	playerBounds := character.getCollisionBoundingBox()
 
 	for _, border := range borderRects {
 		borderBounds := collision.BoundingBox{
@@ -661,7 +833,12 @@ func isBorderColliding(borderRects []image.Rectangle, player *player) bool {
 func getTeleporterCollisionID(teleporterRects map[uint32]image.Rectangle, player *player) uint32 {
 	playerBounds := player.getCollisionBoundingBox()
 
-	for ID, teleporter := range teleporterRects {
+	//checked in a fixed order so touching two teleporters at once always picks the same one
+	for _, ID := range teleporterTileIDs {
+		teleporter, ok := teleporterRects[ID]
+		if !ok {
+			continue
+		}
 		teleporterBounds := collision.BoundingBox{
 			X:      float64(teleporter.Min.X * worldScale),
 			Y:      float64(teleporter.Min.Y * worldScale),
@@ -679,40 +856,34 @@ func (game *rpgGame) changeWorldMap(tileID uint32) {
 	//
 	if tileID == 1 {
 		// go to right world
-		game.levelCurrent = game.levelMaps[1]
-		game.tileHashCurrent = game.tileHashes[1]
+		game.setLevel(teleporterDestinations[tileID])
 		game.player.xLoc = 50
-		game.pathGridCurrent = game.pathGrids[1]
 	} else if tileID == 2 {
 		// go to main world
-		game.levelCurrent = game.levelMaps[2]
-		game.tileHashCurrent = game.tileHashes[2]
+		game.setLevel(teleporterDestinations[tileID])
 		if game.player.xLoc > 600 {
 			game.player.xLoc = 50
 		} else if game.player.xLoc < 150 {
-			game.player.xLoc = game.windowWidth - 100
+			game.player.xLoc = game.logicalWidth*worldScale - 100
 		}
-		game.pathGridCurrent = game.pathGrids[2]
 	} else if tileID == 3 {
 		//go to left world
-		game.levelCurrent = game.levelMaps[0]
-		game.tileHashCurrent = game.tileHashes[0]
-		game.player.xLoc = game.windowWidth - 100
-		game.pathGridCurrent = game.pathGrids[0]
+		game.setLevel(teleporterDestinations[tileID])
+		game.player.xLoc = game.logicalWidth*worldScale - 100
 	}
-	//fmt.Println(game.levelCurrent)
-	game.barrierRect = game.barrierRect[:0]
-	game.teleporterRects = make(map[uint32]image.Rectangle)
+	worldLog.Debug("changed map", "map", game.levelIndex(game.levelCurrent))
 }
 
 func (game *rpgGame) enemiesAttack() {
 	for i := range game.enemies {
-		if game.enemies[i].isPlayerInAttackRange(&game.player) && game.enemies[i].interactCooldown < 0 {
+		if game.enemies[i].isPlayerInAttackRange(&game.player) && game.enemies[i].interactCooldown < 0 &&
+			!game.enemies[i].isStunned() {
 			if game.enemies[i].level == game.levelCurrent {
 				//damage player
-				game.sounds.playerDamaged.playSound()
-				game.player.hitPoints -= game.enemies[i].attackPower
-				game.enemies[i].interactCooldown = COOLDOWN
+				if game.damagePlayer(game.enemies[i].effectiveAttackPower(), game.enemies[i].centerX(), game.enemies[i].centerY()) {
+					game.player.applyStatus(enemyMeleeEffects[game.enemies[i].characterType])
+				}
+				game.enemies[i].interactCooldown = game.settings.AttackCooldown
 			}
 		} else if game.enemies[i].interactCooldown > -10 {
 			game.enemies[i].interactCooldown--
@@ -722,7 +893,7 @@ func (game *rpgGame) enemiesAttack() {
 
 func (game *rpgGame) enemiesPathing() {
 	for i := range game.enemies {
-		if game.enemies[i].level == game.levelCurrent && game.enemies[i].action == PATH {
+		if game.enemies[i].level == game.levelCurrent && game.enemies[i].action == PATH && !game.enemies[i].isKnockedBack() {
 			game.moveCharacterAlongPath(&game.enemies[i])
 		}
 	}
@@ -732,9 +903,20 @@ func (game *rpgGame) itemsPickupCheck() {
 	newDroppedItems := make([]item, 0)
 	for i := range game.droppedItems {
 		if game.player.isItemColliding(&game.droppedItems[i]) && game.droppedItems[i].level == game.levelCurrent {
+			if game.droppedItems[i].raisesMaxHealth > 0 {
+				game.player.raiseMaxHealth(game.droppedItems[i].raisesMaxHealth)
+				game.sounds.play("heal")
+				continue
+			}
+			if game.droppedItems[i].appliesStatus != NOSTATUS {
+				//consumed straight away rather than carried
+				game.player.applyStatus(game.droppedItems[i].appliesStatus)
+				game.sounds.play("heal")
+				continue
+			}
 			game.player.inventory = append(game.player.inventory, game.droppedItems[i])
-			if game.droppedItems[i].displayName != "Heart" {
-				game.sounds.itemPickup.playSound()
+			if game.droppedItems[i].displayName != HeartItem.displayName || game.player.hitPoints >= game.player.maxHitPoints {
+				game.sounds.play("itemPickup")
 			}
 		} else {
 			newDroppedItems = append(newDroppedItems, game.droppedItems[i])
@@ -754,6 +936,12 @@ func (game *rpgGame) updatePath(c *character, player *player) {
 
 	if startCell != nil && endCell != nil {
 		c.path = game.pathGridCurrent.GetPathFromCells(startCell, endCell, false, false)
+		if c.path == nil {
+			aiLog.Debug("no path to player", "from", startCell, "to", endCell)
+		}
+	} else {
+		warnRepeated(aiLog, "path off grid", "enemy or player is off the path grid",
+			"enemyCol", cStartCol, "enemyRow", cStartRow, "playerCol", playerCol, "playerRow", playerRow)
 	}
 }
 
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"github.com/lafriks/go-tiled"
+	"github.com/solarlune/paths"
+	"image"
+	_ "image/png"
+	"io"
+	"maps"
+	"path"
+	"slices"
+)
+
+const (
+	SEVERITYERROR   = "error"
+	SEVERITYWARNING = "warning"
+)
+
+// mapProblem is one thing the map validator found wrong with a map
+type mapProblem struct {
+	file     string
+	severity string
+	message  string
+}
+
+type mapReport struct {
+	problems []mapProblem
+}
+
+func (report *mapReport) errorf(file string, format string, args ...any) {
+	report.problems = append(report.problems, mapProblem{file, SEVERITYERROR, fmt.Sprintf(format, args...)})
+}
+
+func (report *mapReport) warnf(file string, format string, args ...any) {
+	report.problems = append(report.problems, mapProblem{file, SEVERITYWARNING, fmt.Sprintf(format, args...)})
+}
+
+func (report *mapReport) count(severity string) int {
+	total := 0
+	for _, problem := range report.problems {
+		if problem.severity == severity {
+			total++
+		}
+	}
+	return total
+}
+
+// mapEntity is anything placed on a map that has to be able to stand where it is
+type mapEntity struct {
+	name   string
+	level  int
+	bounds image.Rectangle
+	start  bool
+}
+
+// runValidate is the validate subcommand. It checks every level the way the game would load it and prints what it
+// finds, returning 1 if there were any errors so it can fail a CI build.
+func runValidate(args []string, output io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	dataDir := flags.String("data-dir", "", "folder laid out like assets whose files replace or add to the game's own")
+	modsDir := flags.String("mods-dir", DEFAULTMODSDIR, "folder of mods, each a folder laid out like assets")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := setupAssets(*dataDir, *modsDir); err != nil {
+		fmt.Fprintln(output, "setting up assets:", err)
+		return 2
+	}
+
+	report := validateLevels()
+	for _, problem := range report.problems {
+		fmt.Fprintf(output, "%s: %s: %s\n", problem.file, problem.severity, problem.message)
+	}
+	errorCount := report.count(SEVERITYERROR)
+	fmt.Fprintf(output, "%d maps checked, %d errors, %d warnings\n", len(levelFiles), errorCount,
+		report.count(SEVERITYWARNING))
+	if errorCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// validateLevels loads every level without any images, then starts a new game on them so the entities are where a
+// player would find them
+func validateLevels() *mapReport {
+	report := &mapReport{}
+	game, problems := loadHeadlessWorld(func(filename string, gameMap *tiled.Map) bool {
+		pathable := validateMapLayers(report, filename, gameMap)
+		validateTileset(report, filename, gameMap)
+		return pathable
+	})
+	for i, problem := range problems {
+		if problem != nil {
+			report.errorf(levelFiles[i], "%v", problem)
+		}
+	}
+	if game == nil {
+		return report
+	}
+
+	entities := game.mapEntities()
+	for index, filename := range levelFiles {
+		if game.pathGrids[index] == nil {
+			continue
+		}
+		game.validateLevelLayout(report, index, filename, entities)
+	}
+	return report
+}
+
+// validateMapLayers checks the layers have as many tiles as the map and that there is a second layer for
+// makeSearchMap, reporting whether the map can be path found on
+func validateMapLayers(report *mapReport, filename string, gameMap *tiled.Map) bool {
+	usable := true
+	if len(gameMap.Layers) < 2 {
+		report.errorf(filename, "has %d tile layers, the second is needed for collision and path finding",
+			len(gameMap.Layers))
+		usable = false
+	}
+	for _, layer := range gameMap.Layers {
+		if len(layer.Tiles) != gameMap.Width*gameMap.Height {
+			report.errorf(filename, "layer %q has %d tiles but the map is %dx%d", layer.Name, len(layer.Tiles),
+				gameMap.Width, gameMap.Height)
+			usable = false
+		}
+	}
+	return usable
+}
+
+// validateTileset checks every tile comes from the first tileset, which is the only one drawn, and is inside it, and
+// that the tileset's hazard properties name statuses the game has
+func validateTileset(report *mapReport, filename string, gameMap *tiled.Map) {
+	if len(gameMap.Tilesets) == 0 || gameMap.Tilesets[0].Image == nil {
+		report.errorf(filename, "has no tileset image")
+		return
+	}
+	tileset := gameMap.Tilesets[0]
+	imagePath := path.Join("assets", tileset.Image.Source)
+	if file, err := GameAssets.Open(imagePath); err != nil {
+		report.errorf(filename, "tileset image: %v", err)
+	} else {
+		if _, _, err := image.DecodeConfig(file); err != nil {
+			report.errorf(filename, "tileset image %s: %v", imagePath, err)
+		}
+		file.Close()
+	}
+	for _, tile := range tileset.Tiles {
+		if name := tile.Properties.GetString(HAZARDPROPERTY); name != "" {
+			if _, ok := statusNamed(name); !ok {
+				report.errorf(filename, "tileset %q tile %d has hazard %q, which is not a status", tileset.Name, tile.ID,
+					name)
+			}
+		}
+	}
+
+	for _, layer := range gameMap.Layers {
+		reported := map[uint32]bool{}
+		for position, tile := range layer.Tiles {
+			if tile.Nil || reported[tile.ID] {
+				continue
+			}
+			x, y := position%gameMap.Width, position/gameMap.Width
+			if tile.Tileset != tileset {
+				reported[tile.ID] = true
+				report.errorf(filename, "layer %q tile %d,%d is from another tileset, only %q is drawn", layer.Name, x,
+					y, tileset.Name)
+			} else if tile.ID >= uint32(tileset.TileCount) {
+				reported[tile.ID] = true
+				report.errorf(filename, "layer %q tile %d,%d has ID %d but %q only has %d tiles", layer.Name, x, y,
+					tile.ID, tileset.Name, tileset.TileCount)
+			}
+		}
+	}
+}
+
+// mapEntities lists the player, characters, items and campfires, with their bounds in map pixels
+func (game *rpgGame) mapEntities() []mapEntity {
+	entities := []mapEntity{{
+		name:   "player spawn",
+		level:  game.respawn.levelIndex,
+		bounds: characterMapBounds(&game.player.character),
+		start:  true,
+	}, {
+		name:   "quest giver",
+		level:  game.levelIndex(game.questGiver.level),
+		bounds: characterMapBounds(&game.questGiver),
+	}}
+	for i := range game.enemies {
+		entities = append(entities, mapEntity{
+			name:   characterTypeNames[game.enemies[i].characterType],
+			level:  game.levelIndex(game.enemies[i].level),
+			bounds: characterMapBounds(&game.enemies[i]),
+		})
+	}
+	for _, droppedItem := range game.droppedItems {
+		size := droppedItem.picture.Bounds().Size().Mul(resizeScale)
+		entities = append(entities, mapEntity{
+			name:   droppedItem.displayName,
+			level:  game.levelIndex(droppedItem.level),
+			bounds: screenToMapRect(image.Rectangle{Max: size}.Add(image.Pt(droppedItem.xLoc, droppedItem.yLoc))),
+		})
+	}
+	for _, campfire := range game.checkpoints {
+		entities = append(entities, mapEntity{
+			name:  "campfire " + campfire.name,
+			level: game.levelIndex(campfire.level),
+			bounds: screenToMapRect(image.Rect(campfire.xLoc, campfire.yLoc, campfire.xLoc+campfire.width,
+				campfire.yLoc+campfire.height)),
+			start: true,
+		})
+	}
+	return entities
+}
+
+func characterMapBounds(target *character) image.Rectangle {
+	return screenToMapRect(image.Rect(target.xLoc, target.yLoc, target.xLoc+target.FRAME_WIDTH*resizeScale,
+		target.yLoc+target.FRAME_HEIGHT*resizeScale))
+}
+
+// screenToMapRect scales a rectangle on screen down to map pixels
+func screenToMapRect(rect image.Rectangle) image.Rectangle {
+	return image.Rect(rect.Min.X/worldScale, rect.Min.Y/worldScale, rect.Max.X/worldScale, rect.Max.Y/worldScale)
+}
+
+// validateLevelLayout checks nothing starts inside a barrier, every teleporter leads to a map and can be walked to
+// from a campfire or the spawn, and warns about walkable areas that can't be reached at all
+func (game *rpgGame) validateLevelLayout(report *mapReport, index int, filename string, entities []mapEntity) {
+	gameMap := game.levelMaps[index]
+	grid := game.pathGrids[index]
+	barriers := map[image.Point]bool{}
+	var teleporters []image.Point
+	for _, layer := range gameMap.Layers {
+		for position, tile := range layer.Tiles {
+			if tile.Nil {
+				continue
+			}
+			cell := image.Pt(position%gameMap.Width, position/gameMap.Width)
+			if slices.Contains(barrierTileIDs, tile.ID) {
+				barriers[cell] = true
+			}
+			if slices.Contains(teleporterTileIDs, tile.ID) {
+				teleporters = append(teleporters, cell)
+				if destination, ok := teleporterDestinations[tile.ID]; !ok || destination >= len(game.levelMaps) {
+					report.errorf(filename, "teleporter at %d,%d leads to a map that isn't loaded", cell.X, cell.Y)
+				}
+			}
+		}
+	}
+	//the player can't walk through any barrier, even ones the enemies' grid counts as walkable
+	for cell := range barriers {
+		if gridCell := grid.Get(cell.X, cell.Y); gridCell != nil {
+			gridCell.Walkable = false
+		}
+	}
+
+	regions := walkableRegions(grid)
+	startRegions := map[int]bool{}
+	for _, entity := range entities {
+		if entity.level != index {
+			continue
+		}
+		covered := tilesCovered(entity.bounds, gameMap)
+		for _, cell := range covered {
+			if barriers[cell] {
+				report.errorf(filename, "%s at tile %d,%d is inside a barrier", entity.name, cell.X, cell.Y)
+				break
+			}
+		}
+		if !entity.start {
+			continue
+		}
+		for _, cell := range covered {
+			if region, ok := regions[cell]; ok {
+				startRegions[region] = true
+			}
+		}
+	}
+
+	//the player arrives next to a teleporter, so its area can be reached even without a campfire
+	reachable := maps.Clone(startRegions)
+	for _, teleporter := range teleporters {
+		reached := false
+		for _, cell := range append(neighbours(teleporter), teleporter) {
+			if region, ok := regions[cell]; ok {
+				reached = reached || startRegions[region]
+				reachable[region] = true
+			}
+		}
+		if len(startRegions) > 0 && !reached {
+			report.errorf(filename, "teleporter at %d,%d can't be walked to from a campfire or the spawn",
+				teleporter.X, teleporter.Y)
+		}
+	}
+
+	//name each unreachable area by its top left tile so the report is the same every run
+	isolated := map[int]image.Point{}
+	sizes := map[int]int{}
+	for cell, region := range regions {
+		if reachable[region] {
+			continue
+		}
+		sizes[region]++
+		if first, ok := isolated[region]; !ok || cell.Y < first.Y || (cell.Y == first.Y && cell.X < first.X) {
+			isolated[region] = cell
+		}
+	}
+	cells := make([]image.Point, 0, len(isolated))
+	for _, cell := range isolated {
+		cells = append(cells, cell)
+	}
+	slices.SortFunc(cells, func(a, b image.Point) int {
+		if a.Y != b.Y {
+			return cmp.Compare(a.Y, b.Y)
+		}
+		return cmp.Compare(a.X, b.X)
+	})
+	for _, cell := range cells {
+		report.warnf(filename, "%d walkable tiles from %d,%d can't be reached from a campfire, the spawn or a "+
+			"teleporter", sizes[regions[cell]], cell.X, cell.Y)
+	}
+}
+
+// walkableRegions labels every walkable cell of grid with the region it belongs to, cells in the same region being
+// connected without diagonals
+func walkableRegions(grid *paths.Grid) map[image.Point]int {
+	regions := map[image.Point]int{}
+	region := 0
+	for _, start := range grid.CellsByWalkable(true) {
+		startPoint := image.Pt(start.X, start.Y)
+		if _, ok := regions[startPoint]; ok {
+			continue
+		}
+		queue := []image.Point{startPoint}
+		regions[startPoint] = region
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range neighbours(current) {
+				cell := grid.Get(next.X, next.Y)
+				if _, seen := regions[next]; seen || cell == nil || !cell.Walkable {
+					continue
+				}
+				regions[next] = region
+				queue = append(queue, next)
+			}
+		}
+		region++
+	}
+	return regions
+}
+
+func neighbours(cell image.Point) []image.Point {
+	return []image.Point{cell.Add(image.Pt(1, 0)), cell.Add(image.Pt(-1, 0)), cell.Add(image.Pt(0, 1)),
+		cell.Add(image.Pt(0, -1))}
+}
+
+// tilesCovered lists the map tiles a rectangle in map pixels overlaps
+func tilesCovered(bounds image.Rectangle, gameMap *tiled.Map) []image.Point {
+	var covered []image.Point
+	for y := bounds.Min.Y / gameMap.TileHeight; y <= (bounds.Max.Y-1)/gameMap.TileHeight; y++ {
+		for x := bounds.Min.X / gameMap.TileWidth; x <= (bounds.Max.X-1)/gameMap.TileWidth; x++ {
+			covered = append(covered, image.Pt(x, y))
+		}
+	}
+	return covered
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/lafriks/go-tiled"
+	"io"
+	"io/fs"
+	"path"
+)
+
+const (
+	MUSICPROPERTY      = "music"
+	MUSICFADETICKS     = 90
+	COMBATMUSIC        = "combat.wav"
+	DEFAULTBOSSMUSIC   = "boss.wav"
+	MUSICSTOPPEDVOLUME = 0.001
+)
+
+// bossMusic lists the character types that get their own track instead of COMBATMUSIC while they chase the player
+var bossMusic = map[int]string{
+	KING: DEFAULTBOSSMUSIC,
+}
+
+// musicTrack is a looping song streamed from assets/music. fade runs from 0 to 1 as it crossfades in.
+type musicTrack struct {
+	name   string
+	player *audio.Player
+	file   fs.File
+	fade   float64
+}
+
+// openMusicTrack streams assets/music/name as an infinite loop, decoding .ogg or .wav by extension
+func (manager *soundManager) openMusicTrack(name string) (*musicTrack, error) {
+	file, err := GameAssets.Open(path.Join("assets", "music", name))
+	if err != nil {
+		return nil, fmt.Errorf("loading music %s: %w", name, err)
+	}
+	source, ok := file.(io.ReadSeeker)
+	if !ok {
+		file.Close()
+		return nil, fmt.Errorf("%s can't be streamed", name)
+	}
+
+	var loop *audio.InfiniteLoop
+	switch path.Ext(name) {
+	case ".ogg":
+		stream, err := vorbis.DecodeWithSampleRate(soundSampleRate, source)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("interpreting %s: %w", name, err)
+		}
+		loop = audio.NewInfiniteLoop(stream, stream.Length())
+	case ".wav":
+		stream, err := wav.DecodeWithSampleRate(soundSampleRate, source)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("interpreting %s: %w", name, err)
+		}
+		loop = audio.NewInfiniteLoop(stream, stream.Length())
+	default:
+		file.Close()
+		return nil, fmt.Errorf("%s is not an .ogg or .wav file", name)
+	}
+
+	player, err := manager.audioContext.NewPlayer(loop)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("playing %s: %w", name, err)
+	}
+	return &musicTrack{name: name, player: player, file: file}, nil
+}
+
+func (track *musicTrack) close() {
+	track.player.Close()
+	track.file.Close()
+}
+
+// playMusic crossfades from whatever is playing to the named track. An empty name fades the music out.
+func (manager *soundManager) playMusic(name string) {
+	if manager == nil || manager.audioContext == nil {
+		return
+	}
+	if manager.music != nil && manager.music.name == name {
+		return
+	}
+	if manager.music != nil {
+		manager.fadingMusic = append(manager.fadingMusic, manager.music)
+		manager.music = nil
+	}
+	if name == "" {
+		return
+	}
+	//bring a track that is still fading out straight back instead of restarting it
+	for i, track := range manager.fadingMusic {
+		if track.name == name {
+			manager.music = track
+			manager.fadingMusic = append(manager.fadingMusic[:i], manager.fadingMusic[i+1:]...)
+			return
+		}
+	}
+
+	track, err := manager.openMusicTrack(name)
+	if err != nil {
+		audioLog.Error("loading music", "track", name, "err", err)
+		return
+	}
+	track.player.SetVolume(0)
+	track.player.Play()
+	manager.music = track
+}
+
+// updateMusic steps every crossfade, closing tracks once they have faded out completely
+func (manager *soundManager) updateMusic() {
+	step := 1.0 / MUSICFADETICKS
+	if manager.music != nil {
+		manager.music.fade = min(manager.music.fade+step, 1)
+		manager.music.player.SetVolume(manager.busVolume(BUSMUSIC) * manager.music.fade)
+	}
+	fading := manager.fadingMusic[:0]
+	for _, track := range manager.fadingMusic {
+		track.fade -= step
+		if track.fade <= MUSICSTOPPEDVOLUME {
+			track.close()
+			continue
+		}
+		track.player.SetVolume(manager.busVolume(BUSMUSIC) * track.fade)
+		fading = append(fading, track)
+	}
+	manager.fadingMusic = fading
+}
+
+// levelMusic is the track named by a map's "music" property
+func levelMusic(level *tiled.Map) string {
+	if level == nil || level.Properties == nil {
+		return ""
+	}
+	return level.Properties.GetString(MUSICPROPERTY)
+}
+
+// updateMusic picks the track for the current moment. Any enemy chasing the player on this map switches to
+// combat music, or its boss music, and otherwise the map's own track plays.
+func (game *rpgGame) updateMusic() {
+	track := levelMusic(game.levelCurrent)
+	for _, enemy := range game.enemies {
+		if enemy.level != game.levelCurrent || enemy.action != PATH {
+			continue
+		}
+		if boss, ok := bossMusic[enemy.characterType]; ok {
+			track = boss
+			break
+		}
+		track = COMBATMUSIC
+	}
+	game.sounds.playMusic(track)
+}
@@ -1,67 +1,28 @@
 package main
 
 import (
-	"fmt"
 	"github.com/co0p/tankism/lib/collision"
+	"github.com/lafriks/go-tiled"
 	"image"
 )
 
 type player struct {
 	character
-	questProgress int
+	questProgress   int
+	weapon          weapon
+	attackTimer     int
+	attackHits      []int
+	experienceLevel int
+	experience      int
+	levelUpTimer    int
+	//the hazard tile the player is standing on, so its status is applied once on stepping onto it
+	hazardTile  image.Point
+	hazardLevel *tiled.Map
 }
 
+// playerInteractWithCharacterCheck reports whether target is close enough in front of the player to talk to
 func (player *player) playerInteractWithCharacterCheck(target *character) bool {
-	player.updatePlayerInteractionRectangle()
-	fmt.Printf("%d", player.direction)
-	fmt.Printf("player X: %d, Y: %d\n", player.xLoc, player.yLoc)
-	fmt.Printf("itneractbox X: %d, Y: %d  width: %d, height: %d\n", player.interactRect.Min.X, player.interactRect.Min.Y, player.interactRect.Dx()*worldScale, player.interactRect.Dy()*worldScale)
-	targetBounds := target.getCollisionBoundingBox()
-	playerBounds := player.getCollisionBoundingBox()
-	playerInteractBounds := collision.BoundingBox{
-		X:      float64(player.interactRect.Min.X),
-		Y:      float64(player.interactRect.Min.Y),
-		Width:  float64(player.interactRect.Dx()),
-		Height: float64(player.interactRect.Dy()),
-	}
-	if collision.AABBCollision(playerBounds, targetBounds) || collision.AABBCollision(playerInteractBounds, targetBounds) {
-		return true
-	}
-	return false
-}
-
-func (player *player) updatePlayerInteractionRectangle() {
-	//based on direction, change targetRectangle
-	switch player.direction {
-	case DOWN:
-		player.interactRect = image.Rect(
-			player.xLoc,
-			player.yLoc+player.FRAME_HEIGHT*resizeScale,
-			player.xLoc+player.FRAME_WIDTH*resizeScale,
-			player.yLoc+player.FRAME_HEIGHT*resizeScale+player.FRAME_WIDTH,
-		)
-	case RIGHT:
-		player.interactRect = image.Rect(
-			player.xLoc+player.FRAME_WIDTH*resizeScale,
-			player.yLoc,
-			player.xLoc+(player.FRAME_WIDTH*resizeScale*2),
-			player.yLoc+player.FRAME_HEIGHT,
-		)
-	case UP:
-		player.interactRect = image.Rect(
-			player.xLoc,
-			player.yLoc,
-			player.xLoc+(player.FRAME_WIDTH*resizeScale),
-			player.yLoc-(player.FRAME_WIDTH*resizeScale)-player.FRAME_WIDTH,
-		)
-	case LEFT:
-		player.interactRect = image.Rect(
-			player.xLoc,
-			player.yLoc,
-			player.xLoc-player.FRAME_WIDTH*resizeScale,
-			player.yLoc+player.FRAME_HEIGHT,
-		)
-	}
+	return collision.AABBCollision(player.getInteractionProbe(), target.getCollisionBoundingBox())
 }
 
 func (player *player) getInventoryItemIndex(itemName string) int {
@@ -83,21 +44,18 @@ func (player *player) questCheckInventoryForBook() bool {
 	return false
 }
 
+// convertHeartItemsToHealth uses up one carried Heart if the player is missing health, saving the rest for later
 func (player *player) convertHeartItemsToHealth() bool {
-	indexToRemove := 0
-	remove := false
-	for i := range player.inventory {
-		if player.inventory[i].displayName == "Heart" {
-			indexToRemove = i
-			remove = true
-			player.hitPoints++
-			break
-		}
+	if player.hitPoints >= player.maxHitPoints {
+		return false
 	}
-	if remove {
-		player.removeInventoryItemAtIndex(indexToRemove)
+	index := player.getInventoryItemIndex(HeartItem.displayName)
+	if index == -1 {
+		return false
 	}
-	return remove
+	player.heal(HEALTHPERHEART)
+	player.removeInventoryItemAtIndex(index)
+	return true
 }
 
 func (player *player) animatePlayerSprite() {
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"github.com/co0p/tankism/lib/collision"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/lafriks/go-tiled"
+	"image"
+	"math"
+	"slices"
+)
+
+const (
+	PROJECTILERANGE = 300
+)
+
+// projectileType is the shared description of everything fired the same way. pierce is how many extra
+// characters it passes through before disappearing.
+type projectileType struct {
+	picture       image.Image
+	speed         float64
+	lifetime      int
+	pierce        int
+	damage        int
+	cooldown      int
+	appliesStatus int
+}
+
+type projectile struct {
+	projectileType
+	xLoc       float64
+	yLoc       float64
+	velocityX  float64
+	velocityY  float64
+	fromPlayer bool
+	level      *tiled.Map
+	hits       []int
+}
+
+var StoneProjectile = projectileType{
+	picture:       StoneItem.picture,
+	speed:         6,
+	lifetime:      60,
+	pierce:        0,
+	damage:        1,
+	cooldown:      TICKSPERSECOND / 2,
+	appliesStatus: STUN,
+}
+
+var FireballProjectile = projectileType{
+	picture:  grabItemImage(64, 48, 16, 16),
+	speed:    4,
+	lifetime: 90,
+	pierce:   2,
+	damage:   1,
+	cooldown: TICKSPERSECOND * 2,
+}
+
+var MagicBoltProjectile = projectileType{
+	picture:       grabItemImage(176, 48, 16, 16),
+	speed:         3,
+	lifetime:      120,
+	pierce:        0,
+	damage:        1,
+	cooldown:      TICKSPERSECOND * 3,
+	appliesStatus: SLOW,
+}
+
+// enemyRangedAttacks lists the character types that shoot at the player instead of only walking into them
+var enemyRangedAttacks = map[int]projectileType{
+	KING: MagicBoltProjectile,
+}
+
+// fireProjectile launches kind from the center of shooter towards (targetX, targetY)
+func (game *rpgGame) fireProjectile(kind projectileType, shooter *character, level *tiled.Map,
+	targetX, targetY int, fromPlayer bool) {
+
+	size := kind.picture.Bounds().Dx() * resizeScale
+	startX := float64(shooter.centerX() - size/2)
+	startY := float64(shooter.centerY() - size/2)
+	dx := float64(targetX) - float64(shooter.centerX())
+	dy := float64(targetY) - float64(shooter.centerY())
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	game.projectiles = append(game.projectiles, projectile{
+		projectileType: kind,
+		xLoc:           startX,
+		yLoc:           startY,
+		velocityX:      dx / length * kind.speed,
+		velocityY:      dy / length * kind.speed,
+		fromPlayer:     fromPlayer,
+		level:          level,
+	})
+}
+
+// playerFireProjectile throws kind in the direction the player is facing
+func (game *rpgGame) playerFireProjectile(kind projectileType) {
+	targetX, targetY := game.player.centerX(), game.player.centerY()
+	switch game.player.direction {
+	case DOWN:
+		targetY += PROJECTILERANGE
+	case RIGHT:
+		targetX += PROJECTILERANGE
+	case UP:
+		targetY -= PROJECTILERANGE
+	case LEFT:
+		targetX -= PROJECTILERANGE
+	}
+	game.fireProjectile(kind, &game.player.character, game.levelCurrent, targetX, targetY, true)
+	game.player.rangedCooldown = kind.cooldown
+}
+
+// playerRangedAttack throws a stone from the inventory with F, or casts a fireball with E
+func (game *rpgGame) playerRangedAttack() {
+	if game.player.rangedCooldown > 0 {
+		game.player.rangedCooldown--
+		return
+	}
+	if game.player.action == DEAD {
+		return
+	}
+	if game.isKeyPressed(KEYTHROW) {
+		stoneIndex := game.player.getInventoryItemIndex(StoneItem.displayName)
+		if stoneIndex != -1 {
+			game.player.removeInventoryItemAtIndex(stoneIndex)
+			game.playerFireProjectile(StoneProjectile)
+			game.sounds.play("playerInteract")
+		}
+	} else if game.isKeyPressed(KEYCAST) {
+		game.playerFireProjectile(FireballProjectile)
+		game.sounds.play("playerInteract")
+	}
+}
+
+// enemiesRangedAttack has every ranged enemy that is chasing the player shoot at them once its cooldown is up
+func (game *rpgGame) enemiesRangedAttack() {
+	for i := range game.enemies {
+		kind, ok := enemyRangedAttacks[game.enemies[i].characterType]
+		if !ok {
+			continue
+		}
+		if game.enemies[i].rangedCooldown > 0 {
+			game.enemies[i].rangedCooldown--
+		} else if game.enemies[i].action == PATH && game.enemies[i].level == game.levelCurrent {
+			game.fireProjectile(kind, &game.enemies[i], game.enemies[i].level,
+				game.player.centerX(), game.player.centerY(), false)
+			game.enemies[i].rangedCooldown = kind.cooldown
+		}
+	}
+}
+
+func (projectile *projectile) getCollisionBoundingBox() collision.BoundingBox {
+	return collision.BoundingBox{
+		X:      projectile.xLoc,
+		Y:      projectile.yLoc,
+		Width:  float64(projectile.picture.Bounds().Dx() * resizeScale),
+		Height: float64(projectile.picture.Bounds().Dy() * resizeScale),
+	}
+}
+
+// isHittingBarrier checks the projectile against the same barrier tiles the player collides with
+func (projectile *projectile) isHittingBarrier(borderRects []image.Rectangle) bool {
+	projectileBounds := projectile.getCollisionBoundingBox()
+	for _, border := range borderRects {
+		borderBounds := collision.BoundingBox{
+			X:      float64(border.Min.X * worldScale),
+			Y:      float64(border.Min.Y * worldScale),
+			Width:  float64(border.Dx() * worldScale),
+			Height: float64(border.Dy() * worldScale),
+		}
+		if collision.AABBCollision(projectileBounds, borderBounds) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateProjectiles moves every projectile and resolves what it hit. Projectiles that are not on the current
+// map, ran out of lifetime, hit a barrier or used up their pierce are removed.
+func (game *rpgGame) updateProjectiles() {
+	remaining := game.projectiles[:0]
+	for _, shot := range game.projectiles {
+		if shot.level != game.levelCurrent {
+			continue
+		}
+		shot.xLoc += shot.velocityX
+		shot.yLoc += shot.velocityY
+		shot.lifetime--
+		if shot.lifetime <= 0 || shot.isHittingBarrier(game.barrierRect) {
+			continue
+		}
+		if game.projectileHitCheck(&shot) {
+			remaining = append(remaining, shot)
+		}
+	}
+	game.projectiles = remaining
+}
+
+// projectileHitCheck damages whatever shot is touching and reports whether it should keep flying
+func (game *rpgGame) projectileHitCheck(shot *projectile) bool {
+	shotBounds := shot.getCollisionBoundingBox()
+	//knockback pushes away from the middle of the shot
+	shotX := int(shotBounds.X + shotBounds.Width/2)
+	shotY := int(shotBounds.Y + shotBounds.Height/2)
+	if !shot.fromPlayer {
+		if game.player.action != DEAD && collision.AABBCollision(shotBounds, game.player.getCollisionBoundingBox()) {
+			if game.damagePlayer(shot.damage, shotX, shotY) {
+				game.player.applyStatus(shot.appliesStatus)
+			}
+			return false
+		}
+		return true
+	}
+
+	for i := range game.enemies {
+		if game.enemies[i].level != shot.level || game.enemies[i].action == DEAD || slices.Contains(shot.hits, i) {
+			continue
+		}
+		//a hit that doesn't land, on an enemy that is still invulnerable, doesn't use up the shot's pierce
+		if collision.AABBCollision(shotBounds, game.enemies[i].getCollisionBoundingBox()) &&
+			game.damageEnemy(&game.enemies[i], shot.damage, shotX, shotY) {
+			shot.hits = append(shot.hits, i)
+			game.enemies[i].applyStatus(shot.appliesStatus)
+			if len(shot.hits) > shot.pierce {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (game *rpgGame) drawProjectiles(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
+	for _, shot := range game.projectiles {
+		if shot.level == game.levelCurrent {
+			op.GeoM.Reset()
+			op.GeoM.Scale(resizeScale, resizeScale)
+			op.GeoM.Translate(shot.xLoc, shot.yLoc)
+			drawWorldImage(screen, shot.picture.(*ebiten.Image), op)
+		}
+	}
+}
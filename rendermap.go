@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// unwalkableTint is laid over tiles enemies can't path through when the grid is drawn
+var unwalkableTint = color.RGBA{R: 128, A: 128}
+
+// runRenderMap is the render-map subcommand. It draws a map's layers into a PNG the way drawWorld would, without
+// opening a window, so map changes can be looked at and compared in review.
+func runRenderMap(args []string, output io.Writer) int {
+	flags := flag.NewFlagSet("render-map", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: render-map [flags] <map.tmx>")
+		flags.PrintDefaults()
+	}
+	dataDir := flags.String("data-dir", "", "folder laid out like assets whose files replace or add to the game's own")
+	modsDir := flags.String("mods-dir", DEFAULTMODSDIR, "folder of mods, each a folder laid out like assets")
+	outputFile := flags.String("o", "", "PNG to write, the map's name with .png by default")
+	scale := flags.Int("scale", worldScale, "how many pixels across each map pixel is drawn")
+	showEntities := flags.Bool("entities", false, "outline where the player, characters, items and campfires start")
+	showGrid := flags.Bool("grid", false, "shade the tiles enemies can't path through")
+	showTeleporters := flags.Bool("teleporters", false, "outline teleporter tiles")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *scale < 1 {
+		flags.Usage()
+		return 2
+	}
+	if err := setupAssets(*dataDir, *modsDir); err != nil {
+		fmt.Fprintln(output, "setting up assets:", err)
+		return 2
+	}
+
+	mapName := flags.Arg(0)
+	gameMap, err := loadMapFromEmbedded(path.Join(ASSETROOT, mapName))
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return 1
+	}
+	rendered, err := renderMapImage(gameMap)
+	if err != nil {
+		fmt.Fprintf(output, "rendering %s: %v\n", mapName, err)
+		return 1
+	}
+	rendered = scaleNearest(rendered, *scale)
+
+	tileSize := image.Pt(gameMap.TileWidth**scale, gameMap.TileHeight**scale)
+	if *showGrid {
+		if len(gameMap.Layers) < 2 {
+			fmt.Fprintf(output, "%s has no collision layer to draw the grid from\n", mapName)
+		} else {
+			grid := newPathGrid((&worldinfo{}).makeSearchMap(gameMap), gameMap)
+			for _, cell := range grid.CellsByWalkable(false) {
+				tile := image.Rectangle{Max: tileSize}.Add(image.Pt(cell.X*tileSize.X, cell.Y*tileSize.Y))
+				draw.Draw(rendered, tile, image.NewUniform(unwalkableTint), image.Point{}, draw.Over)
+			}
+		}
+	}
+	if *showTeleporters {
+		for _, layer := range gameMap.Layers {
+			for position, tile := range layer.Tiles {
+				if !tile.Nil && slices.Contains(teleporterTileIDs, tile.ID) {
+					cell := image.Pt(position%gameMap.Width*tileSize.X, position/gameMap.Width*tileSize.Y)
+					strokeRect(rendered, image.Rectangle{Max: tileSize}.Add(cell), colornames.Magenta)
+				}
+			}
+		}
+	}
+	if *showEntities {
+		index := slices.Index(levelFiles, strings.TrimPrefix(mapName, "./"))
+		if index == -1 {
+			fmt.Fprintf(output, "%s isn't one of the game's levels so nothing starts on it\n", mapName)
+		} else if entities, err := startingEntities(); err != nil {
+			fmt.Fprintln(output, err)
+			return 1
+		} else {
+			for _, entity := range entities {
+				if entity.level != index {
+					continue
+				}
+				entityColor := color.Color(colornames.Lime)
+				if entity.start {
+					entityColor = colornames.Cyan
+				}
+				bounds := image.Rect(entity.bounds.Min.X**scale, entity.bounds.Min.Y**scale,
+					entity.bounds.Max.X**scale, entity.bounds.Max.Y**scale)
+				strokeRect(rendered, bounds, entityColor)
+			}
+		}
+	}
+
+	fileName := *outputFile
+	if fileName == "" {
+		fileName = strings.TrimSuffix(path.Base(mapName), path.Ext(mapName)) + ".png"
+	}
+	if err := writePNG(fileName, rendered); err != nil {
+		fmt.Fprintln(output, err)
+		return 1
+	}
+	fmt.Fprintln(output, "wrote", fileName)
+	return 0
+}
+
+// renderMapImage composites every layer of gameMap at one pixel per map pixel. Tiles are cut out of the first
+// tileset the same way makeEbitenImagesFromMap does, and tile 0 is left empty like drawWorld leaves it.
+func renderMapImage(gameMap *tiled.Map) (*image.RGBA, error) {
+	if len(gameMap.Tilesets) == 0 || gameMap.Tilesets[0].Image == nil || gameMap.Tilesets[0].Columns <= 0 {
+		return nil, fmt.Errorf("map has no tileset image")
+	}
+	tilesetPath := path.Join(ASSETROOT, gameMap.Tilesets[0].Image.Source)
+	tilesetFile, err := GameAssets.Open(tilesetPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading tileset: %w", err)
+	}
+	defer tilesetFile.Close()
+	tileset, _, err := image.Decode(tilesetFile)
+	if err != nil {
+		return nil, fmt.Errorf("decoding tileset %s: %w", tilesetPath, err)
+	}
+
+	rendered := image.NewRGBA(image.Rect(0, 0, gameMap.Width*gameMap.TileWidth, gameMap.Height*gameMap.TileHeight))
+	for _, layer := range gameMap.Layers {
+		for position, tile := range layer.Tiles {
+			if tile.ID == 0 {
+				continue
+			}
+			x := position % gameMap.Width * gameMap.TileWidth
+			y := position / gameMap.Width * gameMap.TileHeight
+			source := tileSourceRect(gameMap, tile.ID)
+			draw.Draw(rendered, image.Rect(x, y, x+gameMap.TileWidth, y+gameMap.TileHeight), tileset, source.Min,
+				draw.Over)
+		}
+	}
+	return rendered, nil
+}
+
+// startingEntities lists where everything begins in a new game
+func startingEntities() ([]mapEntity, error) {
+	game, problems := loadHeadlessWorld(nil)
+	if game == nil {
+		return nil, errors.Join(problems...)
+	}
+	return game.mapEntities(), nil
+}
+
+// scaleNearest blows source up by a whole number, keeping pixel art sharp
+func scaleNearest(source *image.RGBA, scale int) *image.RGBA {
+	if scale == 1 {
+		return source
+	}
+	bounds := source.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	for y := 0; y < scaled.Bounds().Dy(); y++ {
+		for x := 0; x < scaled.Bounds().Dx(); x++ {
+			scaled.SetRGBA(x, y, source.RGBAAt(bounds.Min.X+x/scale, bounds.Min.Y+y/scale))
+		}
+	}
+	return scaled
+}
+
+// strokeRect outlines rect one pixel wide
+func strokeRect(target draw.Image, rect image.Rectangle, lineColor color.Color) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		target.Set(x, rect.Min.Y, lineColor)
+		target.Set(x, rect.Max.Y-1, lineColor)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		target.Set(rect.Min.X, y, lineColor)
+		target.Set(rect.Max.X-1, y, lineColor)
+	}
+}
+
+func writePNG(fileName string, picture image.Image) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", fileName, err)
+	}
+	if err := png.Encode(file, picture); err != nil {
+		file.Close()
+		return fmt.Errorf("encoding %s: %w", fileName, err)
+	}
+	return file.Close()
+}
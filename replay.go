@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+const (
+	REPLAYVERSION      = 2
+	REPLAYHASHINTERVAL = 60
+)
+
+// replayHeader is the first line of a replay file. It holds everything besides input that changes how a session
+// plays out, so the replay starts from the same place.
+type replayHeader struct {
+	Version        int                        `json:"version"`
+	Seed           int64                      `json:"seed"`
+	StartMap       string                     `json:"startMap,omitempty"`
+	Spawn          string                     `json:"spawn,omitempty"`
+	Difficulty     int                        `json:"difficulty"`
+	TextSpeed      int                        `json:"textSpeed"`
+	DeathPenalty   int                        `json:"deathPenalty"`
+	AttackCooldown int                        `json:"attackCooldown"`
+	SightRange     int                        `json:"sightRange"`
+	Keys           [KEYACTIONCOUNT]ebiten.Key `json:"keys"`
+}
+
+// replayTick is one line after the header, a tick's input and every REPLAYHASHINTERVAL ticks the state hash the
+// game had after it
+type replayTick struct {
+	inputFrame
+	Hash string `json:"hash,omitempty"`
+}
+
+// inputRecorder writes each tick of a session to a replay file as it is played
+type inputRecorder struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newInputRecorder(fileName string, header replayHeader) (*inputRecorder, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("creating replay: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	recorder := &inputRecorder{file: file, writer: writer, encoder: json.NewEncoder(writer)}
+	if err := recorder.encoder.Encode(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("writing replay: %w", err)
+	}
+	return recorder, nil
+}
+
+func (recorder *inputRecorder) record(tick replayTick) {
+	if err := recorder.encoder.Encode(tick); err != nil {
+		warnRepeated(gameLog, "recording replay", "recording replay", "err", err)
+	}
+}
+
+func (recorder *inputRecorder) Close() error {
+	if err := recorder.writer.Flush(); err != nil {
+		recorder.file.Close()
+		return fmt.Errorf("writing replay: %w", err)
+	}
+	return recorder.file.Close()
+}
+
+// inputReplay feeds a recorded session back in one tick at a time, checking the game stays in step with it
+type inputReplay struct {
+	header     replayHeader
+	ticks      []replayTick
+	next       int
+	desyncTick int
+}
+
+func loadReplay(fileName string) (*inputReplay, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("opening replay: %w", err)
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	replay := &inputReplay{desyncTick: -1}
+	if err := decoder.Decode(&replay.header); err != nil {
+		return nil, fmt.Errorf("reading replay %s: %w", fileName, err)
+	}
+	if replay.header.Version != REPLAYVERSION {
+		return nil, fmt.Errorf("replay %s is version %d, this game plays version %d", fileName,
+			replay.header.Version, REPLAYVERSION)
+	}
+	for {
+		var tick replayTick
+		if err := decoder.Decode(&tick); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading replay %s tick %d: %w", fileName, len(replay.ticks), err)
+		}
+		replay.ticks = append(replay.ticks, tick)
+	}
+	return replay, nil
+}
+
+// replayHeader describes how this session started, for recording it
+func (game *rpgGame) replayHeader(options launchOptions) replayHeader {
+	return replayHeader{
+		Version:        REPLAYVERSION,
+		Seed:           game.random.seed,
+		StartMap:       options.startMap,
+		Spawn:          options.spawn,
+		Difficulty:     game.settings.Difficulty,
+		TextSpeed:      game.settings.TextSpeed,
+		DeathPenalty:   game.settings.DeathPenalty,
+		AttackCooldown: game.settings.AttackCooldown,
+		SightRange:     game.settings.SightRange,
+		Keys:           game.settings.keys,
+	}
+}
+
+// startReplay sets the game up the way the recorded session started and feeds it the recording from then on. The
+// settings it changes are not saved.
+func (game *rpgGame) startReplay(replay *inputReplay, options launchOptions) error {
+	header := replay.header
+	_, knownDifficulty := difficulties[header.Difficulty]
+	_, knownTextSpeed := textSpeeds[header.TextSpeed]
+	_, knownDeathPenalty := deathPenaltyNames[header.DeathPenalty]
+	if !knownDifficulty || !knownTextSpeed || !knownDeathPenalty ||
+		header.AttackCooldown < MINATTACKCOOLDOWN || header.AttackCooldown > MAXATTACKCOOLDOWN ||
+		header.SightRange < MINSIGHTRANGE || header.SightRange > MAXSIGHTRANGE {
+		return errors.New("replay was recorded with settings this game doesn't have")
+	}
+	game.settings.Difficulty = header.Difficulty
+	game.settings.TextSpeed = header.TextSpeed
+	game.settings.DeathPenalty = header.DeathPenalty
+	game.settings.AttackCooldown = header.AttackCooldown
+	game.settings.SightRange = header.SightRange
+	game.settings.keys = header.Keys
+	options.seed = header.Seed
+	options.startMap = header.StartMap
+	options.spawn = header.Spawn
+	options.skipTitle = true
+	if err := game.applyLaunchOptions(options); err != nil {
+		return err
+	}
+	game.replay = replay
+	return nil
+}
+
+// nextInputFrame is the input for this tick, from the replay while there is one and from ebiten otherwise. A
+// headless run has no window to read, so nothing is pressed.
+func (game *rpgGame) nextInputFrame() inputFrame {
+	if game.replay == nil && game.headless {
+		return inputFrame{}
+	}
+	if game.replay == nil {
+		return game.captureInputFrame()
+	}
+	if game.replay.next < len(game.replay.ticks) {
+		return game.replay.ticks[game.replay.next].inputFrame
+	}
+	gameLog.Info("replay finished", "ticks", len(game.replay.ticks), "desynced", game.replay.desyncTick != -1)
+	game.replay = nil
+	if game.headless {
+		return inputFrame{}
+	}
+	return game.captureInputFrame()
+}
+
+// endTick records or checks the tick that just ran. Every REPLAYHASHINTERVAL ticks the state is hashed so a replay
+// can tell when it stops matching what was recorded.
+func (game *rpgGame) endTick(frame inputFrame) {
+	game.ticks++
+	hash := ""
+	if game.ticks%REPLAYHASHINTERVAL == 0 && (game.recorder != nil || game.replay != nil) {
+		hash = game.stateHash()
+	}
+	if game.recorder != nil {
+		game.recorder.record(replayTick{inputFrame: frame, Hash: hash})
+	}
+	if game.replay != nil {
+		recorded := game.replay.ticks[game.replay.next].Hash
+		if recorded != "" && hash != "" && recorded != hash && game.replay.desyncTick == -1 {
+			game.replay.desyncTick = game.replay.next
+			gameLog.Warn("replay desynced", "tick", game.replay.next, "recorded", recorded, "hash", hash)
+		}
+		game.replay.next++
+	}
+}
+
+// stateHash sums up where everything in the world is and how it is doing, for telling whether two runs match
+func (game *rpgGame) stateHash() string {
+	hash := fnv.New64a()
+	player := &game.player
+	fmt.Fprintln(hash, game.levelIndex(game.levelCurrent), player.xLoc, player.yLoc, player.direction, player.action,
+		player.hitPoints, player.maxHitPoints, player.attackPower, player.questProgress, player.experienceLevel,
+		player.experience, len(player.inventory), len(player.statusEffects), game.respawn)
+	for _, enemy := range game.enemies {
+		fmt.Fprintln(hash, enemy.characterType, game.levelIndex(enemy.level), enemy.xLoc, enemy.yLoc, enemy.action,
+			enemy.hitPoints, len(enemy.statusEffects))
+	}
+	for _, droppedItem := range game.droppedItems {
+		fmt.Fprintln(hash, droppedItem.displayName, game.levelIndex(droppedItem.level), droppedItem.xLoc,
+			droppedItem.yLoc)
+	}
+	for _, thrown := range game.projectiles {
+		fmt.Fprintln(hash, game.levelIndex(thrown.level), thrown.xLoc, thrown.yLoc, thrown.fromPlayer)
+	}
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// runVerifyReplay is the verify-replay subcommand. It plays a replay back without a window and fails if the game
+// stops matching the state hashes recorded with it, so recorded sessions can be kept as regression tests.
+func runVerifyReplay(args []string, output io.Writer) int {
+	flags := flag.NewFlagSet("verify-replay", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: verify-replay [flags] <replay file>")
+		flags.PrintDefaults()
+	}
+	dataDir := flags.String("data-dir", "", "folder laid out like assets whose files replace or add to the game's own")
+	modsDir := flags.String("mods-dir", DEFAULTMODSDIR, "folder of mods, each a folder laid out like assets")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if err := setupAssets(*dataDir, *modsDir); err != nil {
+		fmt.Fprintln(output, "setting up assets:", err)
+		return 2
+	}
+	replay, err := loadReplay(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return 2
+	}
+	game, problems := newGame()
+	if len(problems) > 0 {
+		fmt.Fprintln(output, "loading the game:", errors.Join(problems...))
+		return 2
+	}
+	if err := game.startReplay(replay, launchOptions{}); err != nil {
+		fmt.Fprintln(output, err)
+		return 2
+	}
+
+	checked, err := game.playReplay()
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return 1
+	}
+	fmt.Fprintf(output, "%d ticks replayed, %d state hashes matched\n", replay.next, checked)
+	return 0
+}
+
+// playReplay updates the game without drawing until the replay it was started with runs out, and says how many
+// state hashes were checked along the way. It stops with an error at the first tick that doesn't match.
+func (game *rpgGame) playReplay() (int, error) {
+	replay := game.replay
+	checked := 0
+	for game.replay != nil && replay.next < len(replay.ticks) {
+		if replay.ticks[replay.next].Hash != "" {
+			checked++
+		}
+		if err := game.Update(); errors.Is(err, ebiten.Termination) {
+			break
+		} else if err != nil {
+			return checked, fmt.Errorf("tick %d: %w", replay.next, err)
+		}
+		if replay.desyncTick != -1 {
+			return checked, fmt.Errorf("desynced at tick %d of %d", replay.desyncTick, len(replay.ticks))
+		}
+	}
+	return checked, nil
+}
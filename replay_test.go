@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"path/filepath"
+	"testing"
+)
+
+// newTestGame loads the game with settings kept in a temporary folder, so a player's own settings don't change it
+func newTestGame(t *testing.T) *rpgGame {
+	t.Helper()
+	configDirectory := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDirectory)
+	t.Setenv("AppData", configDirectory)
+	t.Setenv("HOME", configDirectory)
+	game, problems := newGame()
+	if len(problems) > 0 {
+		t.Fatalf("loading the game: %v", problems)
+	}
+	return game
+}
+
+// scriptedTicks holds each key for its number of ticks, one after the other
+func scriptedTicks(script []ebiten.Key, holdTicks []int) []replayTick {
+	var ticks []replayTick
+	for i, key := range script {
+		for tick := 0; tick < holdTicks[i]; tick++ {
+			ticks = append(ticks, replayTick{inputFrame: inputFrame{Keys: []ebiten.Key{key}}})
+		}
+	}
+	return ticks
+}
+
+func TestRecordedReplayVerifies(t *testing.T) {
+	header := replayHeader{
+		Version:        REPLAYVERSION,
+		Seed:           1,
+		Difficulty:     DIFFICULTYNORMAL,
+		TextSpeed:      TEXTNORMAL,
+		DeathPenalty:   PENALTYDROPINVENTORY,
+		AttackCooldown: 60,
+		SightRange:     350,
+		Keys:           defaultKeys,
+	}
+	//walks left into the wall on the edge of the starting map, attacks, then walks up
+	script := scriptedTicks([]ebiten.Key{ebiten.KeyA, ebiten.KeySpace, ebiten.KeyW}, []int{150, 10, 80})
+	replayFile := filepath.Join(t.TempDir(), "session.replay")
+
+	game := newTestGame(t)
+	if err := game.startReplay(&inputReplay{header: header, ticks: script, desyncTick: -1}, launchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := newInputRecorder(replayFile, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.recorder = recorder
+	for i := 0; i < 150; i++ {
+		if err := game.Update(); err != nil {
+			t.Fatalf("tick %d: %v", i, err)
+		}
+	}
+	//without the wall the player would have walked 450 pixels left from 400 by now
+	if game.levelIndex(game.levelCurrent) != 2 || game.player.xLoc < 0 {
+		t.Errorf("walking left ended on map %d at x %d, want map 2 stopped by the wall",
+			game.levelIndex(game.levelCurrent), game.player.xLoc)
+	}
+	if _, err := game.playReplay(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := loadReplay(replayFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded.ticks) != len(script) {
+		t.Fatalf("recorded %d ticks, want %d", len(recorded.ticks), len(script))
+	}
+	replayed := newTestGame(t)
+	if err := replayed.startReplay(recorded, launchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checked, err := replayed.playReplay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked == 0 {
+		t.Error("no state hashes were checked")
+	}
+	if replayed.stateHash() != game.stateHash() {
+		t.Error("replayed game ended in a different state to the recorded one")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// names of the random streams each subsystem draws from. Anything new that is random must draw from
+// game.random.stream with a name from here, never from math/rand's top level functions or the clock, or replays and
+// -seed stop repeating.
+const (
+	RNGLOOT     = "loot"
+	RNGAIWANDER = "aiwander" //where idle enemies wander to
+	RNGCRITS    = "crits"    //whether a hit is critical
+	RNGMAPS     = "maps"     //procedurally generated maps
+)
+
+// gameRandom is the game's only source of randomness. Each subsystem draws from its own named stream, seeded from the
+// game's seed and the stream's name, so extra rolls in one subsystem don't change what any other gets. The same seed
+// and the same input always play out the same way. There are no save files, so the seed is only kept in the
+// seed field of settings.json and in the header of each recording.
+type gameRandom struct {
+	seed    int64
+	streams map[string]*rand.Rand
+}
+
+func newGameRandom(seed int64) *gameRandom {
+	return &gameRandom{seed: seed, streams: map[string]*rand.Rand{}}
+}
+
+// chooseSeed is the first seed that isn't 0, or one from the clock if they all are
+func chooseSeed(seeds ...int64) int64 {
+	for _, seed := range seeds {
+		if seed != 0 {
+			return seed
+		}
+	}
+	return time.Now().UnixNano()
+}
+
+// stream is the random stream called name, started the first time it is asked for
+func (random *gameRandom) stream(name string) *rand.Rand {
+	if stream, ok := random.streams[name]; ok {
+		return stream
+	}
+	nameHash := fnv.New64a()
+	nameHash.Write([]byte(name))
+	stream := rand.New(rand.NewPCG(uint64(random.seed), nameHash.Sum64()))
+	random.streams[name] = stream
+	return stream
+}
+
+func init() {
+	registerConsoleCommand(consoleCommand{
+		name:  "seed",
+		usage: "",
+		help:  "shows the seed this run's randomness comes from, for -seed",
+		run: func(game *rpgGame, args []string) (string, error) {
+			return "seed " + strconv.FormatInt(game.random.seed, 10), nil
+		},
+	})
+}
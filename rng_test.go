@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// draws takes count numbers from the stream called name
+func draws(random *gameRandom, name string, count int) []uint64 {
+	numbers := make([]uint64, count)
+	for i := range numbers {
+		numbers[i] = random.stream(name).Uint64()
+	}
+	return numbers
+}
+
+func TestStreamsAreDeterministic(t *testing.T) {
+	first := draws(newGameRandom(42), RNGLOOT, 10)
+	second := draws(newGameRandom(42), RNGLOOT, 10)
+	if !slices.Equal(first, second) {
+		t.Errorf("seed 42 drew %v then %v", first, second)
+	}
+	if other := draws(newGameRandom(43), RNGLOOT, 10); slices.Equal(first, other) {
+		t.Error("seeds 42 and 43 drew the same numbers")
+	}
+}
+
+func TestStreamsAreIndependent(t *testing.T) {
+	quiet := newGameRandom(7)
+	busy := newGameRandom(7)
+	draws(busy, "other", 100)
+	if got, want := draws(busy, RNGLOOT, 10), draws(quiet, RNGLOOT, 10); !slices.Equal(got, want) {
+		t.Errorf("drawing from another stream changed %s from %v to %v", RNGLOOT, want, got)
+	}
+	if slices.Equal(draws(newGameRandom(7), RNGLOOT, 10), draws(newGameRandom(7), "other", 10)) {
+		t.Error("streams with different names drew the same numbers")
+	}
+}
+
+func TestChooseSeed(t *testing.T) {
+	if seed := chooseSeed(0, 5, 6); seed != 5 {
+		t.Errorf("chooseSeed(0, 5, 6) = %d, want 5", seed)
+	}
+	if seed := chooseSeed(0, 0); seed == 0 {
+		t.Error("chooseSeed(0, 0) = 0, want a seed from the clock")
+	}
+}
+
+func TestNewGameRestartsStreams(t *testing.T) {
+	game := newTestGame(t)
+	game.random = newGameRandom(99)
+	game.startNewGame()
+	first := draws(game.random, RNGLOOT, 10)
+	game.startNewGame()
+	if second := draws(game.random, RNGLOOT, 10); !slices.Equal(first, second) {
+		t.Errorf("a second new game with seed 99 drew %v, the first drew %v", second, first)
+	}
+	if game.random.seed != 99 {
+		t.Errorf("new game changed the seed to %d", game.random.seed)
+	}
+}
+
+func TestStreamNamesAreDistinct(t *testing.T) {
+	names := []string{RNGLOOT, RNGAIWANDER, RNGCRITS, RNGMAPS}
+	for i, name := range names {
+		if slices.Contains(names[i+1:], name) {
+			t.Errorf("two subsystems share the %q stream", name)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// MENULINEHEIGHT is how far apart menu options are drawn, on the logical screen
+const MENULINEHEIGHT = 14
+
+// scene is one layer of the game's state stack. Only the top scene is updated, but every scene is drawn
+// from the bottom up so menus can sit over the world.
+type scene interface {
+	update(game *rpgGame) error
+	draw(game *rpgGame, screen *ebiten.Image)
+}
+
+func (game *rpgGame) pushScene(s scene) {
+	game.scenes = append(game.scenes, s)
+}
+
+func (game *rpgGame) popScene() {
+	if len(game.scenes) > 1 {
+		game.scenes = game.scenes[:len(game.scenes)-1]
+	}
+}
+
+// replaceScenes clears the whole stack down to s
+func (game *rpgGame) replaceScenes(s scene) {
+	game.scenes = []scene{s}
+}
+
+// gameplayScene is the world itself
+type gameplayScene struct{}
+
+func (gameplay *gameplayScene) update(game *rpgGame) error {
+	if game.keyJustPressed(ebiten.KeyEscape) {
+		game.pushScene(newPauseScene())
+		return nil
+	}
+	if game.keyJustPressed(CONSOLETOGGLEKEY) {
+		game.pushScene(game.consoleScene())
+		return nil
+	}
+	game.updateWorld()
+	game.updateMusic()
+	if game.player.action == DEAD {
+		//the world's music only plays while the world does, retrying picks it back up
+		game.sounds.playMusic("")
+		game.pushScene(newGameOverScene())
+	}
+	return nil
+}
+
+func (gameplay *gameplayScene) draw(game *rpgGame, screen *ebiten.Image) {
+	game.drawWorld(screen)
+}
+
+// menuOption is a line in a menuScene. enabled may be nil for options that can always be chosen. Options with a
+// value show it after their label and are changed with adjust, stepping left or right.
+type menuOption struct {
+	label   string
+	enabled func(game *rpgGame) bool
+	choose  func(game *rpgGame) error
+	value   func(game *rpgGame) string
+	adjust  func(game *rpgGame, step int)
+}
+
+// menuScene is a vertical list of options picked with W/S or the arrow keys and chosen with Enter or Space.
+// overlay menus dim whatever is under them instead of clearing the screen.
+type menuScene struct {
+	title      string
+	options    []menuOption
+	selected   int
+	overlay    bool
+	back       func(game *rpgGame) error
+	cursor     image.Point
+	cursorSeen bool
+}
+
+func (menu *menuScene) update(game *rpgGame) error {
+	if game.keyJustPressed(ebiten.KeyEscape) && menu.back != nil {
+		return menu.back(game)
+	}
+	if game.keyJustPressed(ebiten.KeyW) || game.keyJustPressed(ebiten.KeyArrowUp) {
+		menu.moveSelection(game, -1)
+	} else if game.keyJustPressed(ebiten.KeyS) || game.keyJustPressed(ebiten.KeyArrowDown) {
+		menu.moveSelection(game, 1)
+	}
+	//the mouse only takes over the selection once it moves, so it doesn't fight the keyboard
+	if cursor := game.cursorPosition(); cursor != menu.cursor {
+		moved := menu.cursorSeen
+		menu.cursor = cursor
+		menu.cursorSeen = true
+		if i := menu.optionAt(game, cursor); moved && i != -1 && menu.options[i].isEnabled(game) {
+			menu.selected = i
+		}
+	}
+	clicks := game.justTappedPositions()
+	if game.clicked() {
+		clicks = append(clicks, game.cursorPosition())
+	}
+	for _, click := range clicks {
+		if i := menu.optionAt(game, click); i != -1 && menu.options[i].isEnabled(game) {
+			menu.selected = i
+			return menu.options[i].activate(game)
+		}
+	}
+
+	option := menu.options[menu.selected]
+	if !option.isEnabled(game) {
+		return nil
+	}
+	if option.adjust != nil {
+		if game.keyJustPressed(ebiten.KeyA) || game.keyJustPressed(ebiten.KeyArrowLeft) {
+			option.adjust(game, -1)
+		} else if game.keyJustPressed(ebiten.KeyD) || game.keyJustPressed(ebiten.KeyArrowRight) {
+			option.adjust(game, 1)
+		}
+	}
+	if game.keyJustPressed(ebiten.KeyEnter) || game.keyJustPressed(ebiten.KeySpace) {
+		return option.activate(game)
+	}
+	return nil
+}
+
+// activate chooses the option, or steps its value forward if it has nothing to choose
+func (option *menuOption) activate(game *rpgGame) error {
+	if option.choose != nil {
+		return option.choose(game)
+	} else if option.adjust != nil {
+		option.adjust(game, 1)
+	}
+	return nil
+}
+
+// optionsTop is the y of the first option. Long menus start higher up so they still fit on screen.
+func (menu *menuScene) optionsTop(game *rpgGame) int {
+	return min(game.logicalHeight/2, game.logicalHeight-MENULINEHEIGHT-len(menu.options)*MENULINEHEIGHT)
+}
+
+// optionAt is the index of the option drawn at point on the logical screen, or -1 if there isn't one
+func (menu *menuScene) optionAt(game *rpgGame, point image.Point) int {
+	if point.X < 0 || point.X >= game.logicalWidth {
+		return -1
+	}
+	//options are drawn centred on their y, MENULINEHEIGHT apart
+	offset := point.Y - menu.optionsTop(game) + MENULINEHEIGHT/2
+	if offset < 0 || offset/MENULINEHEIGHT >= len(menu.options) {
+		return -1
+	}
+	return offset / MENULINEHEIGHT
+}
+
+// moveSelection steps through the options in direction, skipping disabled ones
+func (menu *menuScene) moveSelection(game *rpgGame, direction int) {
+	for range menu.options {
+		menu.selected = (menu.selected + direction + len(menu.options)) % len(menu.options)
+		if menu.options[menu.selected].isEnabled(game) {
+			return
+		}
+	}
+}
+
+func (option *menuOption) isEnabled(game *rpgGame) bool {
+	return option.enabled == nil || option.enabled(game)
+}
+
+func (menu *menuScene) draw(game *rpgGame, screen *ebiten.Image) {
+	if menu.overlay {
+		vector.DrawFilledRect(screen, 0, 0, float32(game.logicalWidth), float32(game.logicalHeight),
+			color.RGBA{A: 160}, false)
+	} else {
+		screen.Fill(colornames.Black)
+	}
+	centerX := game.logicalWidth / 2
+	top := menu.optionsTop(game)
+	DrawCenteredText(screen, game.fontLarge, menu.title, centerX, min(game.logicalHeight/3, top-2*MENULINEHEIGHT))
+	for i, option := range menu.options {
+		label := option.label
+		if option.value != nil {
+			label += ": " + option.value(game)
+		}
+		textColor := color.Color(colornames.White)
+		if !option.isEnabled(game) {
+			textColor = colornames.Gray
+		}
+		y := top + i*MENULINEHEIGHT
+		if i == menu.selected {
+			//the markers go either side of the label rather than in it, so long labels still fit
+			textColor = colornames.Gold
+			halfWidth := text.BoundString(game.fontSmall, label).Dx() / 2
+			DrawCenteredTextColor(screen, game.fontSmall, ">", centerX-halfWidth-8, y, textColor)
+			DrawCenteredTextColor(screen, game.fontSmall, "<", centerX+halfWidth+8, y, textColor)
+		}
+		DrawCenteredTextColor(screen, game.fontSmall, label, centerX, y, textColor)
+	}
+}
+
+// showTitle clears the stack down to the title screen, fading out the world's music
+func (game *rpgGame) showTitle() {
+	game.sounds.playMusic("")
+	game.replaceScenes(newTitleScene())
+}
+
+func newTitleScene() *menuScene {
+	return &menuScene{
+		title: "Micro RPG",
+		options: []menuOption{
+			{label: "New Game", choose: func(game *rpgGame) error {
+				game.startNewGame()
+				game.replaceScenes(&gameplayScene{})
+				return nil
+			}},
+			{label: "Continue", enabled: func(game *rpgGame) bool {
+				return game.inProgress && game.player.action != DEAD
+			}, choose: func(game *rpgGame) error {
+				game.replaceScenes(&gameplayScene{})
+				return nil
+			}},
+			{label: "Settings", choose: func(game *rpgGame) error {
+				game.pushScene(newSettingsScene())
+				return nil
+			}},
+			{label: "Quit", choose: func(game *rpgGame) error {
+				return ebiten.Termination
+			}},
+		},
+	}
+}
+
+func newPauseScene() *menuScene {
+	resume := func(game *rpgGame) error {
+		game.popScene()
+		return nil
+	}
+	return &menuScene{
+		title:   "PAUSED",
+		overlay: true,
+		back:    resume,
+		options: []menuOption{
+			{label: "Resume", choose: resume},
+			{label: "Settings", choose: func(game *rpgGame) error {
+				game.pushScene(newSettingsScene())
+				return nil
+			}},
+			{label: "Return to Title", choose: func(game *rpgGame) error {
+				game.showTitle()
+				return nil
+			}},
+			{label: "Quit", choose: func(game *rpgGame) error {
+				return ebiten.Termination
+			}},
+		},
+	}
+}
+
+func newGameOverScene() *menuScene {
+	return &menuScene{
+		title:   "GAME OVER",
+		overlay: true,
+		options: []menuOption{
+			{label: "Retry", choose: func(game *rpgGame) error {
+				game.respawnPlayer()
+				game.replaceScenes(&gameplayScene{})
+				return nil
+			}},
+			{label: "Return to Title", choose: func(game *rpgGame) error {
+				game.showTitle()
+				return nil
+			}},
+		},
+	}
+}
+
+// newSettingsScene lets the player change their settings, applying each change as it is made and saving them all
+// once they leave
+func newSettingsScene() *menuScene {
+	back := func(game *rpgGame) error {
+		game.saveSettings()
+		game.popScene()
+		return nil
+	}
+	return &menuScene{
+		title:   "SETTINGS",
+		overlay: true,
+		back:    back,
+		options: []menuOption{
+			{label: "Window Scale", value: func(game *rpgGame) string {
+				return strconv.Itoa(game.settings.WindowScale) + "x"
+			}, adjust: func(game *rpgGame, step int) {
+				game.settings.WindowScale = min(max(game.settings.WindowScale+step, MINWINDOWSCALE), MAXWINDOWSCALE)
+				game.applySettings()
+			}},
+			toggleOption("Fullscreen", func(config *settings) *bool { return &config.Fullscreen }),
+			toggleOption("Vsync", func(config *settings) *bool { return &config.Vsync }),
+			volumeOption("Master Volume", func(config *settings) *float64 { return &config.MasterVolume }),
+			volumeOption("Effects Volume", func(config *settings) *float64 { return &config.SfxVolume }),
+			volumeOption("Music Volume", func(config *settings) *float64 { return &config.MusicVolume }),
+			{label: "Difficulty", value: func(game *rpgGame) string {
+				return difficulties[game.settings.Difficulty].displayName
+			}, adjust: func(game *rpgGame, step int) {
+				game.settings.Difficulty = cycleSetting(game.settings.Difficulty, step, len(difficulties))
+			}},
+			{label: "Text Speed", value: func(game *rpgGame) string {
+				return textSpeeds[game.settings.TextSpeed].displayName
+			}, adjust: func(game *rpgGame, step int) {
+				game.settings.TextSpeed = cycleSetting(game.settings.TextSpeed, step, len(textSpeeds))
+			}},
+			{label: "Death Penalty", value: func(game *rpgGame) string {
+				return deathPenaltyNames[game.settings.DeathPenalty]
+			}, adjust: func(game *rpgGame, step int) {
+				game.settings.DeathPenalty = cycleSetting(game.settings.DeathPenalty, step, len(deathPenaltyNames))
+			}},
+			{label: "Controls", choose: func(game *rpgGame) error {
+				game.pushScene(newControlsScene())
+				return nil
+			}},
+			{label: "Back", choose: back},
+		},
+	}
+}
+
+// cycleSetting steps value through 0 to count-1, wrapping around at either end
+func cycleSetting(value int, step int, count int) int {
+	return (value + step + count) % count
+}
+
+func toggleOption(label string, setting func(config *settings) *bool) menuOption {
+	return menuOption{label: label, value: func(game *rpgGame) string {
+		if *setting(&game.settings) {
+			return "On"
+		}
+		return "Off"
+	}, adjust: func(game *rpgGame, step int) {
+		*setting(&game.settings) = !*setting(&game.settings)
+		game.applySettings()
+	}}
+}
+
+// volumeOption changes a volume in steps of 10%
+func volumeOption(label string, setting func(config *settings) *float64) menuOption {
+	return menuOption{label: label, value: func(game *rpgGame) string {
+		return strconv.Itoa(int(math.Round(*setting(&game.settings)*100))) + "%"
+	}, adjust: func(game *rpgGame, step int) {
+		volume := setting(&game.settings)
+		*volume = min(max(math.Round(*volume*10+float64(step))/10, 0), 1)
+		game.applySettings()
+	}}
+}
+
+// newControlsScene lists every key binding, choosing one waits for the new key to bind to it
+func newControlsScene() *menuScene {
+	back := func(game *rpgGame) error {
+		game.popScene()
+		return nil
+	}
+	options := make([]menuOption, 0, KEYACTIONCOUNT+2)
+	for action := range keyActionLabels {
+		action := action
+		options = append(options, menuOption{label: keyActionLabels[action], value: func(game *rpgGame) string {
+			return game.settings.keys[action].String()
+		}, choose: func(game *rpgGame) error {
+			game.pushScene(&rebindScene{action: action})
+			return nil
+		}})
+	}
+	options = append(options,
+		menuOption{label: "Reset to Defaults", choose: func(game *rpgGame) error {
+			game.settings.keys = defaultKeys
+			return nil
+		}},
+		menuOption{label: "Back", choose: back},
+	)
+	return &menuScene{
+		title:   "CONTROLS",
+		overlay: true,
+		back:    back,
+		options: options,
+	}
+}
+
+// rebindScene waits for the next key press and binds it to action. Escape cancels.
+type rebindScene struct {
+	action int
+}
+
+func (rebind *rebindScene) update(game *rpgGame) error {
+	if game.keyJustPressed(ebiten.KeyEscape) {
+		game.popScene()
+		return nil
+	}
+	if keys := game.justPressedKeys(); len(keys) > 0 {
+		game.settings.bindKey(rebind.action, keys[0])
+		game.popScene()
+	}
+	return nil
+}
+
+func (rebind *rebindScene) draw(game *rpgGame, screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, float32(game.logicalWidth), float32(game.logicalHeight),
+		color.RGBA{A: 200}, false)
+	DrawCenteredText(screen, game.fontSmall, "Press a key for\n"+keyActionLabels[rebind.action],
+		game.logicalWidth/2, game.logicalHeight/2)
+	DrawCenteredTextColor(screen, game.fontSmall, "Escape to cancel", game.logicalWidth/2,
+		game.logicalHeight/2+2*MENULINEHEIGHT, colornames.Gray)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	DIFFICULTYEASY = iota
+	DIFFICULTYNORMAL
+	DIFFICULTYHARD
+)
+
+const (
+	TEXTSLOW = iota
+	TEXTNORMAL
+	TEXTFAST
+	TEXTINSTANT
+)
+
+// key binding actions, indexes into settings.keys
+const (
+	KEYUP = iota
+	KEYDOWN
+	KEYLEFT
+	KEYRIGHT
+	KEYATTACK
+	KEYTHROW
+	KEYCAST
+	KEYMUTE
+	KEYFULLSCREEN
+	KEYACTIONCOUNT
+)
+
+const (
+	MINWINDOWSCALE    = 1
+	MAXWINDOWSCALE    = 4
+	MINATTACKCOOLDOWN = 10 //ticks between attacks, for the player and enemies
+	MAXATTACKCOOLDOWN = 240
+	MINSIGHTRANGE     = 150 //world pixels an enemy notices the player from on Normal
+	MAXSIGHTRANGE     = 800
+	SETTINGSDIRECTORY = "MicroRPG"
+	SETTINGSFILENAME  = "settings.json"
+)
+
+// difficulty changes how hard enemies hit the player, and how much further than the SightRange setting enemies
+// notice the player from
+type difficulty struct {
+	displayName string
+	damageTaken float64
+	sightBonus  int
+}
+
+var difficulties = map[int]difficulty{
+	DIFFICULTYEASY:   {displayName: "Easy", damageTaken: 0.5, sightBonus: -100},
+	DIFFICULTYNORMAL: {displayName: "Normal", damageTaken: 1, sightBonus: 0},
+	DIFFICULTYHARD:   {displayName: "Hard", damageTaken: 1.5, sightBonus: 100},
+}
+
+// textSpeed is how many characters of dialogue appear each tick, 0 showing it all at once
+type textSpeed struct {
+	displayName  string
+	charsPerTick float64
+}
+
+var textSpeeds = map[int]textSpeed{
+	TEXTSLOW:    {displayName: "Slow", charsPerTick: 0.25},
+	TEXTNORMAL:  {displayName: "Normal", charsPerTick: 0.5},
+	TEXTFAST:    {displayName: "Fast", charsPerTick: 1},
+	TEXTINSTANT: {displayName: "Instant"},
+}
+
+var deathPenaltyNames = map[int]string{
+	PENALTYNONE:           "None",
+	PENALTYDROPINVENTORY:  "Drop Items",
+	PENALTYLOSEEXPERIENCE: "Lose XP",
+}
+
+// keyActionNames are the names actions are saved under in the settings file
+var keyActionNames = [KEYACTIONCOUNT]string{
+	KEYUP:         "up",
+	KEYDOWN:       "down",
+	KEYLEFT:       "left",
+	KEYRIGHT:      "right",
+	KEYATTACK:     "attack",
+	KEYTHROW:      "throw",
+	KEYCAST:       "cast",
+	KEYMUTE:       "mute",
+	KEYFULLSCREEN: "fullscreen",
+}
+
+var keyActionLabels = [KEYACTIONCOUNT]string{
+	KEYUP:         "Move Up",
+	KEYDOWN:       "Move Down",
+	KEYLEFT:       "Move Left",
+	KEYRIGHT:      "Move Right",
+	KEYATTACK:     "Attack/Interact",
+	KEYTHROW:      "Throw Stone",
+	KEYCAST:       "Cast Fireball",
+	KEYMUTE:       "Mute",
+	KEYFULLSCREEN: "Fullscreen",
+}
+
+var defaultKeys = [KEYACTIONCOUNT]ebiten.Key{
+	KEYUP:         ebiten.KeyW,
+	KEYDOWN:       ebiten.KeyS,
+	KEYLEFT:       ebiten.KeyA,
+	KEYRIGHT:      ebiten.KeyD,
+	KEYATTACK:     ebiten.KeySpace,
+	KEYTHROW:      ebiten.KeyF,
+	KEYCAST:       ebiten.KeyE,
+	KEYMUTE:       ebiten.KeyM,
+	KEYFULLSCREEN: ebiten.KeyF11,
+}
+
+// settings is everything the player can change from the settings menu. It is saved as json in the user's
+// config directory, key bindings being saved by action and key name. Seed, AttackCooldown and SightRange aren't in
+// the menu, they are set by editing the file, the seed to make every run play out the same and the others to tune
+// combat. With no save files, this and the replay header are the only places a seed is kept.
+type settings struct {
+	WindowScale    int               `json:"windowScale"`
+	Fullscreen     bool              `json:"fullscreen"`
+	Vsync          bool              `json:"vsync"`
+	MasterVolume   float64           `json:"masterVolume"`
+	SfxVolume      float64           `json:"sfxVolume"`
+	MusicVolume    float64           `json:"musicVolume"`
+	Difficulty     int               `json:"difficulty"`
+	TextSpeed      int               `json:"textSpeed"`
+	DeathPenalty   int               `json:"deathPenalty"`
+	AttackCooldown int               `json:"attackCooldown"`
+	SightRange     int               `json:"sightRange"`
+	KeyBindings    map[string]string `json:"keyBindings"`
+	Seed           int64             `json:"seed,omitempty"`
+
+	keys [KEYACTIONCOUNT]ebiten.Key
+}
+
+func defaultSettings() settings {
+	return settings{
+		WindowScale:    3,
+		Vsync:          true,
+		MasterVolume:   1,
+		SfxVolume:      1,
+		MusicVolume:    0.6,
+		Difficulty:     DIFFICULTYNORMAL,
+		TextSpeed:      TEXTNORMAL,
+		DeathPenalty:   PENALTYDROPINVENTORY,
+		AttackCooldown: 60,
+		SightRange:     350,
+		keys:           defaultKeys,
+	}
+}
+
+// settingsPath is where the settings file lives, normally under the user's config directory
+func settingsPath() string {
+	configDirectory, err := os.UserConfigDir()
+	if err != nil {
+		return SETTINGSFILENAME
+	}
+	return filepath.Join(configDirectory, SETTINGSDIRECTORY, SETTINGSFILENAME)
+}
+
+// loadSettings reads the settings file over the defaults, so a missing file or missing fields keep their
+// default values. Anything out of range is put back to its default.
+func loadSettings() settings {
+	loaded := defaultSettings()
+	data, err := os.ReadFile(settingsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			gameLog.Error("reading settings", "err", err)
+		}
+		return loaded
+	}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		gameLog.Error("interpreting settings, using defaults", "err", err)
+		return defaultSettings()
+	}
+	loaded.validate()
+	return loaded
+}
+
+// validate replaces any value the game can't use with its default
+func (config *settings) validate() {
+	defaults := defaultSettings()
+	if config.WindowScale < MINWINDOWSCALE || config.WindowScale > MAXWINDOWSCALE {
+		config.WindowScale = defaults.WindowScale
+	}
+	config.MasterVolume = clampVolume(config.MasterVolume, defaults.MasterVolume)
+	config.SfxVolume = clampVolume(config.SfxVolume, defaults.SfxVolume)
+	config.MusicVolume = clampVolume(config.MusicVolume, defaults.MusicVolume)
+	if _, ok := difficulties[config.Difficulty]; !ok {
+		config.Difficulty = defaults.Difficulty
+	}
+	if _, ok := textSpeeds[config.TextSpeed]; !ok {
+		config.TextSpeed = defaults.TextSpeed
+	}
+	if _, ok := deathPenaltyNames[config.DeathPenalty]; !ok {
+		config.DeathPenalty = defaults.DeathPenalty
+	}
+	if config.AttackCooldown < MINATTACKCOOLDOWN || config.AttackCooldown > MAXATTACKCOOLDOWN {
+		config.AttackCooldown = defaults.AttackCooldown
+	}
+	if config.SightRange < MINSIGHTRANGE || config.SightRange > MAXSIGHTRANGE {
+		config.SightRange = defaults.SightRange
+	}
+
+	config.keys = defaultKeys
+	for action, name := range keyActionNames {
+		keyName, ok := config.KeyBindings[name]
+		if !ok {
+			continue
+		}
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(keyName)); err != nil {
+			gameLog.Warn("unknown key binding, using default", "action", name, "key", keyName,
+				"default", defaultKeys[action].String())
+			continue
+		}
+		config.keys[action] = key
+	}
+}
+
+func clampVolume(volume float64, fallback float64) float64 {
+	if math.IsNaN(volume) {
+		return fallback
+	}
+	return min(max(volume, 0), 1)
+}
+
+// save writes the settings file, creating its directory if it needs to
+func (config *settings) save() error {
+	config.KeyBindings = make(map[string]string, KEYACTIONCOUNT)
+	for action, name := range keyActionNames {
+		config.KeyBindings[name] = config.keys[action].String()
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	filePath := settingsPath()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0o644)
+}
+
+// bindKey binds action to key. If another action already used key it takes over action's old key instead.
+func (config *settings) bindKey(action int, key ebiten.Key) {
+	for other := range config.keys {
+		if other != action && config.keys[other] == key {
+			config.keys[other] = config.keys[action]
+		}
+	}
+	config.keys[action] = key
+}
+
+// applySettings pushes the current settings to the window and the sound buses
+func (game *rpgGame) applySettings() {
+	ebiten.SetWindowSize(game.logicalWidth*game.settings.WindowScale, game.logicalHeight*game.settings.WindowScale)
+	ebiten.SetFullscreen(game.settings.Fullscreen)
+	ebiten.SetVsyncEnabled(game.settings.Vsync)
+	game.sounds.setVolume(BUSMASTER, game.settings.MasterVolume)
+	game.sounds.setVolume(BUSSFX, game.settings.SfxVolume)
+	game.sounds.setVolume(BUSMUSIC, game.settings.MusicVolume)
+}
+
+func (game *rpgGame) saveSettings() {
+	if err := game.settings.save(); err != nil {
+		gameLog.Error("saving settings", "err", err)
+	}
+}
+
+func (game *rpgGame) difficulty() difficulty {
+	return difficulties[game.settings.Difficulty]
+}
+
+// sightRange is how close in world pixels the player has to be for an enemy to notice them
+func (game *rpgGame) sightRange() int {
+	return game.settings.SightRange + game.difficulty().sightBonus
+}
+
+// isKeyPressed reports whether the key bound to action is held down
+func (game *rpgGame) isKeyPressed(action int) bool {
+	return game.keyPressed(game.settings.keys[action])
+}
+
+// revealedText is the part of s that has typed out after ticks at the player's text speed
+func (game *rpgGame) revealedText(s string, ticks int) string {
+	speed := textSpeeds[game.settings.TextSpeed].charsPerTick
+	if speed <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	shown := int(float64(ticks) * speed)
+	if shown >= len(runes) {
+		return s
+	}
+	return string(runes[:shown])
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestValidateResetsTuningOutOfRange(t *testing.T) {
+	defaults := defaultSettings()
+	config := defaultSettings()
+	config.AttackCooldown = MAXATTACKCOOLDOWN + 1
+	config.SightRange = MINSIGHTRANGE - 1
+	config.validate()
+	if config.AttackCooldown != defaults.AttackCooldown || config.SightRange != defaults.SightRange {
+		t.Errorf("validate left attackCooldown %d and sightRange %d, want the defaults %d and %d",
+			config.AttackCooldown, config.SightRange, defaults.AttackCooldown, defaults.SightRange)
+	}
+	config.AttackCooldown = MINATTACKCOOLDOWN
+	config.SightRange = MAXSIGHTRANGE
+	config.validate()
+	if config.AttackCooldown != MINATTACKCOOLDOWN || config.SightRange != MAXSIGHTRANGE {
+		t.Errorf("validate changed attackCooldown %d and sightRange %d, which are in range", config.AttackCooldown,
+			config.SightRange)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const (
+	BUSMASTER = iota
+	BUSSFX
+	BUSMUSIC
+)
+
+const MAXSOUNDVOICES = 32
+
+// soundManager keeps every effect decoded in memory and gives each play its own short lived player, so the same
+// sound can overlap itself. Sounds are looked up by their file name without the extension.
+type soundManager struct {
+	audioContext *audio.Context
+	clips        map[string][]byte
+	volumes      [3]float64
+	muted        bool
+	voices       []*soundVoice
+	music        *musicTrack
+	fadingMusic  []*musicTrack
+}
+
+// soundVoice is one playing effect. gain is its own volume before the bus volumes, lower for distant sounds.
+type soundVoice struct {
+	player *audio.Player
+	gain   float64
+}
+
+func newSoundManager(context *audio.Context) *soundManager {
+	return &soundManager{
+		audioContext: context,
+		clips:        make(map[string][]byte),
+		volumes:      [3]float64{BUSMASTER: 1, BUSSFX: 1, BUSMUSIC: 0.6},
+	}
+}
+
+// loadEmbeddedSounds decodes every .wav in assets/sounds, carrying on past ones that fail and returning their errors
+func (manager *soundManager) loadEmbeddedSounds() error {
+	entries, err := fs.ReadDir(GameAssets, path.Join("assets", "sounds"))
+	if err != nil {
+		return fmt.Errorf("listing sounds: %w", err)
+	}
+	var failed []error
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".wav" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".wav")
+		if err := manager.loadEmbeddedWav(name, entry.Name()); err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// loadEmbeddedWav decodes assets/sounds/fileName into PCM bytes stored under name
+func (manager *soundManager) loadEmbeddedWav(name string, fileName string) error {
+	file, err := GameAssets.Open(path.Join("assets", "sounds", fileName))
+	if err != nil {
+		return fmt.Errorf("loading sound %s: %w", fileName, err)
+	}
+	defer file.Close()
+	soundWav, err := wav.DecodeWithoutResampling(file)
+	if err != nil {
+		return fmt.Errorf("interpreting %s: %w", fileName, err)
+	}
+	pcm, err := io.ReadAll(soundWav)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", fileName, err)
+	}
+	manager.clips[name] = pcm
+	return nil
+}
+
+// play starts a new voice of the named effect on the SFX bus, unpanned and at full volume. Unknown names play nothing.
+func (manager *soundManager) play(name string) {
+	manager.startVoice(name, false, 0, 1)
+}
+
+// playPositioned plays the named effect panned from -1 (left) to 1 (right) with its volume scaled by gain
+func (manager *soundManager) playPositioned(name string, pan float64, gain float64) {
+	manager.startVoice(name, true, pan, gain)
+}
+
+// startVoice plays the named effect from soundStream, with its volume scaled by gain
+func (manager *soundManager) startVoice(name string, positioned bool, pan float64, gain float64) {
+	if manager == nil || manager.audioContext == nil || manager.muted {
+		return
+	}
+	pcm, ok := manager.clips[name]
+	if !ok || len(pcm) == 0 {
+		warnRepeated(audioLog, "unknown sound "+name, "no sound loaded with this name", "sound", name)
+		return
+	}
+	if len(manager.voices) >= MAXSOUNDVOICES {
+		warnRepeated(audioLog, "voices full", "too many sounds playing, dropping one", "sound", name)
+		return
+	}
+	//centred positioned sounds are panned too, so they are as loud as ones just off centre
+	player, err := manager.audioContext.NewPlayer(soundStream(pcm, positioned, pan))
+	if err != nil {
+		audioLog.Error("playing sound", "sound", name, "err", err)
+		return
+	}
+	voice := &soundVoice{player: player, gain: gain}
+	player.SetVolume(manager.busVolume(BUSSFX) * gain)
+	player.Play()
+	manager.voices = append(manager.voices, voice)
+}
+
+// update closes voices that have finished playing and steps music crossfades, it should be called once per tick
+func (manager *soundManager) update() {
+	if manager == nil {
+		return
+	}
+	manager.updateMusic()
+	playing := manager.voices[:0]
+	for _, voice := range manager.voices {
+		if voice.player.IsPlaying() {
+			playing = append(playing, voice)
+		} else {
+			voice.player.Close()
+		}
+	}
+	manager.voices = playing
+}
+
+// busVolume is the volume a player on bus should use, after the master volume and mute
+func (manager *soundManager) busVolume(bus int) float64 {
+	if manager.muted {
+		return 0
+	}
+	if bus == BUSMASTER {
+		return manager.volumes[BUSMASTER]
+	}
+	return manager.volumes[BUSMASTER] * manager.volumes[bus]
+}
+
+func (manager *soundManager) setVolume(bus int, volume float64) {
+	manager.volumes[bus] = min(max(volume, 0), 1)
+	manager.applyVolumes()
+}
+
+func (manager *soundManager) toggleMute() {
+	manager.muted = !manager.muted
+	manager.applyVolumes()
+}
+
+// applyVolumes pushes volume changes to effects and music that are already playing
+func (manager *soundManager) applyVolumes() {
+	for _, voice := range manager.voices {
+		voice.player.SetVolume(manager.busVolume(BUSSFX) * voice.gain)
+	}
+	if manager.music != nil {
+		manager.music.player.SetVolume(manager.busVolume(BUSMUSIC) * manager.music.fade)
+	}
+	for _, track := range manager.fadingMusic {
+		track.player.SetVolume(manager.busVolume(BUSMUSIC) * track.fade)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/lafriks/go-tiled"
+	"io"
+	"math"
+)
+
+const (
+	SOUNDFULLVOLUMEDISTANCE = 48.0
+	SOUNDHEARINGDISTANCE    = 480.0
+	SOUNDPANDISTANCE        = 360.0
+	SOUNDMAXPAN             = 0.8
+)
+
+// soundAttenuation is the volume, from 0 to 1, of a sound distance screen pixels away from the listener.
+// Sounds within SOUNDFULLVOLUMEDISTANCE play at full volume and fade linearly to silence at SOUNDHEARINGDISTANCE.
+func soundAttenuation(distance float64) float64 {
+	if distance <= SOUNDFULLVOLUMEDISTANCE {
+		return 1
+	}
+	if distance >= SOUNDHEARINGDISTANCE {
+		return 0
+	}
+	return 1 - (distance-SOUNDFULLVOLUMEDISTANCE)/(SOUNDHEARINGDISTANCE-SOUNDFULLVOLUMEDISTANCE)
+}
+
+// soundPan turns how far a source is to the right of the listener into a pan from -SOUNDMAXPAN (left) to
+// SOUNDMAXPAN (right), so a sound is never completely silent in one ear
+func soundPan(offsetX float64) float64 {
+	pan := offsetX / SOUNDPANDISTANCE
+	return min(max(pan, -1), 1) * SOUNDMAXPAN
+}
+
+// panGains splits pan into left and right channel gains with an equal power curve, so a sound keeps the same
+// loudness as it moves across the screen. A centred sound gets 1/√2 in each ear and neither gain goes above 1, so
+// panning never makes a sample clip.
+func panGains(pan float64) (float64, float64) {
+	angle := (min(max(pan, -1), 1) + 1) * math.Pi / 4
+	return math.Cos(angle), math.Sin(angle)
+}
+
+// pannedStream plays 16 bit stereo PCM with each channel scaled by its own gain
+type pannedStream struct {
+	source    *bytes.Reader
+	leftGain  float64
+	rightGain float64
+	//the rest of a frame that didn't fit in the last Read
+	pending []byte
+}
+
+func newPannedStream(pcm []byte, pan float64) *pannedStream {
+	left, right := panGains(pan)
+	return &pannedStream{source: bytes.NewReader(pcm), leftGain: left, rightGain: right}
+}
+
+// soundStream is what a voice of pcm plays from. Only sounds placed in the world are panned, everything else plays
+// at unity gain in both ears so menu and pickup sounds are as loud as they were recorded.
+func soundStream(pcm []byte, positioned bool, pan float64) io.ReadSeeker {
+	if !positioned {
+		return bytes.NewReader(pcm)
+	}
+	return newPannedStream(pcm, pan)
+}
+
+func (stream *pannedStream) Read(p []byte) (int, error) {
+	if len(stream.pending) > 0 {
+		n := copy(p, stream.pending)
+		stream.pending = stream.pending[n:]
+		return n, nil
+	}
+	if len(p) < 4 {
+		//too small for a frame, scale a whole one and hand it out a piece at a time
+		frame := make([]byte, 4)
+		n, err := io.ReadFull(stream.source, frame)
+		if n == 0 {
+			return 0, err
+		}
+		stream.scaleFrames(frame[:n])
+		copied := copy(p, frame[:n])
+		stream.pending = frame[copied:n]
+		return copied, nil
+	}
+	//only hand out whole frames so every sample lines up with its channel
+	p = p[:len(p)/4*4]
+	n, err := stream.source.Read(p)
+	stream.scaleFrames(p[:n])
+	return n, err
+}
+
+// scaleFrames applies the channel gains to every whole frame in pcm
+func (stream *pannedStream) scaleFrames(pcm []byte) {
+	for i := 0; i+3 < len(pcm); i += 4 {
+		scaleSample(pcm[i:i+2], stream.leftGain)
+		scaleSample(pcm[i+2:i+4], stream.rightGain)
+	}
+}
+
+func (stream *pannedStream) Seek(offset int64, whence int) (int64, error) {
+	stream.pending = nil
+	return stream.source.Seek(offset, whence)
+}
+
+// scaleSample multiplies the little endian 16 bit sample in b by gain, clipping instead of wrapping around
+func scaleSample(b []byte, gain float64) {
+	sample := float64(int16(binary.LittleEndian.Uint16(b))) * gain
+	sample = min(max(sample, math.MinInt16), math.MaxInt16)
+	binary.LittleEndian.PutUint16(b, uint16(int16(sample)))
+}
+
+// playSoundAt plays the named effect as if it came from (x, y) on level, heard from the player. Sounds on
+// other maps or out of earshot are not played at all.
+func (game *rpgGame) playSoundAt(name string, level *tiled.Map, x, y int) {
+	if level != game.levelCurrent {
+		return
+	}
+	offsetX := float64(x - game.player.centerX())
+	offsetY := float64(y - game.player.centerY())
+	volume := soundAttenuation(math.Hypot(offsetX, offsetY))
+	if volume <= 0 {
+		return
+	}
+	game.sounds.playPositioned(name, soundPan(offsetX), volume)
+}
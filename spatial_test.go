@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+const gainTolerance = 1e-9
+
+func TestSoundAttenuation(t *testing.T) {
+	tests := []struct {
+		distance float64
+		want     float64
+	}{
+		{distance: 0, want: 1},
+		{distance: SOUNDFULLVOLUMEDISTANCE, want: 1},
+		{distance: (SOUNDFULLVOLUMEDISTANCE + SOUNDHEARINGDISTANCE) / 2, want: 0.5},
+		{distance: SOUNDHEARINGDISTANCE, want: 0},
+		{distance: SOUNDHEARINGDISTANCE * 2, want: 0},
+	}
+	for _, test := range tests {
+		if got := soundAttenuation(test.distance); math.Abs(got-test.want) > gainTolerance {
+			t.Errorf("soundAttenuation(%v) = %v, want %v", test.distance, got, test.want)
+		}
+	}
+}
+
+func TestSoundPan(t *testing.T) {
+	tests := []struct {
+		offsetX float64
+		want    float64
+	}{
+		{offsetX: 0, want: 0},
+		{offsetX: SOUNDPANDISTANCE / 2, want: SOUNDMAXPAN / 2},
+		{offsetX: -SOUNDPANDISTANCE / 2, want: -SOUNDMAXPAN / 2},
+		{offsetX: SOUNDPANDISTANCE * 3, want: SOUNDMAXPAN},
+		{offsetX: -SOUNDPANDISTANCE * 3, want: -SOUNDMAXPAN},
+	}
+	for _, test := range tests {
+		if got := soundPan(test.offsetX); math.Abs(got-test.want) > gainTolerance {
+			t.Errorf("soundPan(%v) = %v, want %v", test.offsetX, got, test.want)
+		}
+	}
+}
+
+func TestPanGains(t *testing.T) {
+	tests := []struct {
+		pan         float64
+		left, right float64
+	}{
+		{pan: 0, left: 1 / math.Sqrt2, right: 1 / math.Sqrt2},
+		{pan: -1, left: 1, right: 0},
+		{pan: 1, left: 0, right: 1},
+		{pan: -5, left: 1, right: 0},
+		{pan: 5, left: 0, right: 1},
+	}
+	for _, test := range tests {
+		left, right := panGains(test.pan)
+		if math.Abs(left-test.left) > gainTolerance || math.Abs(right-test.right) > gainTolerance {
+			t.Errorf("panGains(%v) = %v, %v, want %v, %v", test.pan, left, right, test.left, test.right)
+		}
+	}
+	for pan := -1.0; pan <= 1; pan += 0.125 {
+		left, right := panGains(pan)
+		if left > 1 || right > 1 {
+			t.Errorf("panGains(%v) = %v, %v, a gain above 1 would clip", pan, left, right)
+		}
+		if power := left*left + right*right; math.Abs(power-1) > gainTolerance {
+			t.Errorf("panGains(%v) has power %v, want 1", pan, power)
+		}
+	}
+}
+
+// stereoPCM is frames of 16 bit stereo PCM, each frame a left then a right sample
+func stereoPCM(frames ...[2]int16) []byte {
+	pcm := make([]byte, 0, len(frames)*4)
+	for _, frame := range frames {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(frame[0]))
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(frame[1]))
+	}
+	return pcm
+}
+
+// readAll reads stream to the end bufferSize bytes at a time
+func readAll(t *testing.T, stream io.Reader, bufferSize int) []byte {
+	t.Helper()
+	var read []byte
+	buffer := make([]byte, bufferSize)
+	for range 1000 {
+		n, err := stream.Read(buffer)
+		read = append(read, buffer[:n]...)
+		if errors.Is(err, io.EOF) {
+			return read
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("reading %d bytes at a time never finished", bufferSize)
+	return nil
+}
+
+func TestPannedStreamRead(t *testing.T) {
+	source := stereoPCM([2]int16{1000, 1000}, [2]int16{-2000, 2000}, [2]int16{math.MaxInt16, math.MinInt16})
+	tests := []struct {
+		name       string
+		pan        float64
+		bufferSize int
+		want       []byte
+	}{
+		{
+			name:       "hard left",
+			pan:        -1,
+			bufferSize: 64,
+			want:       stereoPCM([2]int16{1000, 0}, [2]int16{-2000, 0}, [2]int16{math.MaxInt16, 0}),
+		},
+		{
+			name:       "hard right",
+			pan:        1,
+			bufferSize: 64,
+			want:       stereoPCM([2]int16{0, 1000}, [2]int16{0, 2000}, [2]int16{0, math.MinInt16}),
+		},
+		{
+			name:       "centre",
+			pan:        0,
+			bufferSize: 64,
+			want:       stereoPCM([2]int16{707, 707}, [2]int16{-1414, 1414}, [2]int16{23169, -23170}),
+		},
+		{
+			//reads that aren't a whole number of frames still scale each sample by its own channel's gain
+			name:       "buffer of 6 bytes",
+			pan:        -1,
+			bufferSize: 6,
+			want:       stereoPCM([2]int16{1000, 0}, [2]int16{-2000, 0}, [2]int16{math.MaxInt16, 0}),
+		},
+		{
+			name:       "buffer of 3 bytes",
+			pan:        1,
+			bufferSize: 3,
+			want:       stereoPCM([2]int16{0, 1000}, [2]int16{0, 2000}, [2]int16{0, math.MinInt16}),
+		},
+		{
+			name:       "buffer of 1 byte",
+			pan:        -1,
+			bufferSize: 1,
+			want:       stereoPCM([2]int16{1000, 0}, [2]int16{-2000, 0}, [2]int16{math.MaxInt16, 0}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pcm := append([]byte(nil), source...)
+			got := readAll(t, newPannedStream(pcm, test.pan), test.bufferSize)
+			if string(got) != string(test.want) {
+				t.Errorf("read % x, want % x", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPannedStreamSeek(t *testing.T) {
+	stream := newPannedStream(stereoPCM([2]int16{1000, 1000}, [2]int16{2000, 2000}), -1)
+	buffer := make([]byte, 1)
+	if _, err := stream.Read(buffer); err != nil {
+		t.Fatal(err)
+	}
+	//seeking drops what was left of the half read frame
+	if _, err := stream.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readAll(t, stream, 4), stereoPCM([2]int16{2000, 0}); string(got) != string(want) {
+		t.Errorf("read % x after seeking, want % x", got, want)
+	}
+}
+
+func TestSoundStreamGains(t *testing.T) {
+	source := stereoPCM([2]int16{1000, -1000}, [2]int16{math.MaxInt16, math.MinInt16})
+	//play() isn't positioned, so it must come out exactly as recorded rather than 1/√2 quieter in each ear
+	if got := readAll(t, soundStream(append([]byte(nil), source...), false, 0), 64); string(got) != string(source) {
+		t.Errorf("an unpositioned sound read % x, want it unchanged at % x", got, source)
+	}
+	want := stereoPCM([2]int16{707, -707}, [2]int16{23169, -23170})
+	if got := readAll(t, soundStream(append([]byte(nil), source...), true, 0), 64); string(got) != string(want) {
+		t.Errorf("a positioned sound at the centre read % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/colornames"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+const (
+	NOSTATUS = iota
+	POISON
+	SLOW
+	STUN
+	REGENERATION
+)
+
+const (
+	STACKREFRESH   = iota //reapplying resets the duration
+	STACKINTENSITY        //reapplying adds a stack, multiplying the effect, up to maxStacks
+	STACKIGNORE           //reapplying does nothing until the effect wears off
+)
+
+// statusDefinition is the data shared by every instance of a status. damagePerTick is negative for healing,
+// and the modifiers are added to the character's stats once per stack while the effect lasts.
+type statusDefinition struct {
+	duration       int
+	tickInterval   int
+	damagePerTick  int
+	speedModifier  int
+	attackModifier int
+	stun           bool
+	stacking       int
+	maxStacks      int
+	icon           image.Image
+	tint           color.Color //multiplies the icon's colours when it is drawn, nil to draw it as it is
+}
+
+type statusEffect struct {
+	kind      int
+	remaining int
+	tickTimer int
+	stacks    int
+}
+
+// status icons the sprite sheets have nothing for, drawn in white and grey so their tint colours them. '#' is white,
+// '+' is the grey outline and anything else is see through.
+var (
+	poisonGlyph = []string{
+		"................",
+		".......++.......",
+		"......+##+......",
+		"......+##+......",
+		".....+####+.....",
+		".....+####+.....",
+		"....+######+....",
+		"....+######+....",
+		"...+########+...",
+		"...+########+...",
+		"...+########+...",
+		"...+########+...",
+		"....+######+....",
+		".....+####+.....",
+		"......++++......",
+		"................",
+	}
+	slowGlyph = []string{
+		"................",
+		"................",
+		"...++++++++++...",
+		"....+######+....",
+		"....+######+....",
+		".....+####+.....",
+		"......+##+......",
+		".......++.......",
+		".......++.......",
+		"......+..+......",
+		".....+....+.....",
+		"....+..##..+....",
+		"....+.####.+....",
+		"...++++++++++...",
+		"................",
+		"................",
+	}
+)
+
+// statusGlyph turns rows of a glyph like poisonGlyph into an icon
+func statusGlyph(rows []string) image.Image {
+	glyph := image.NewRGBA(image.Rect(0, 0, HUDICONSIZE, HUDICONSIZE))
+	for y, row := range rows {
+		for x, pixel := range row {
+			switch pixel {
+			case '#':
+				glyph.Set(x, y, color.White)
+			case '+':
+				glyph.Set(x, y, color.Gray{Y: 110})
+			}
+		}
+	}
+	return ebiten.NewImageFromImage(glyph)
+}
+
+var statusDefinitions = map[int]statusDefinition{
+	POISON: {
+		duration:      TICKSPERSECOND * 5,
+		tickInterval:  TICKSPERSECOND,
+		damagePerTick: 1,
+		stacking:      STACKINTENSITY,
+		maxStacks:     3,
+		icon:          statusGlyph(poisonGlyph),
+		tint:          colornames.Mediumorchid,
+	},
+	SLOW: {
+		duration:      TICKSPERSECOND * 3,
+		speedModifier: -2,
+		stacking:      STACKREFRESH,
+		maxStacks:     1,
+		icon:          statusGlyph(slowGlyph),
+		tint:          colornames.Lightskyblue,
+	},
+	STUN: {
+		duration:  TICKSPERSECOND,
+		stun:      true,
+		stacking:  STACKIGNORE,
+		maxStacks: 1,
+		icon:      grabItemImage(32, 128, 16, 16),
+	},
+	REGENERATION: {
+		duration:      TICKSPERSECOND * 6,
+		tickInterval:  TICKSPERSECOND * 2,
+		damagePerTick: -1,
+		stacking:      STACKREFRESH,
+		maxStacks:     1,
+		icon:          grabItemImage(0, 48, 16, 16),
+	},
+}
+
+// enemyMeleeEffects lists the character types whose touch applies a status as well as damage
+var enemyMeleeEffects = map[int]int{
+	LEPRECHAUN: POISON,
+}
+
+// HAZARDPROPERTY is the tileset tile property naming the status a tile gives the player stepping onto it, as in
+// hazard=poison on the bones and skulls of the overworld tileset
+const HAZARDPROPERTY = "hazard"
+
+// applyStatus gives character the effect kind, following that status's stacking rule if it already has it
+func (character *character) applyStatus(kind int) {
+	definition, ok := statusDefinitions[kind]
+	if !ok {
+		return
+	}
+	for i := range character.statusEffects {
+		effect := &character.statusEffects[i]
+		if effect.kind != kind {
+			continue
+		}
+		switch definition.stacking {
+		case STACKREFRESH:
+			effect.remaining = definition.duration
+		case STACKINTENSITY:
+			effect.remaining = definition.duration
+			if effect.stacks < definition.maxStacks {
+				effect.stacks++
+			}
+		}
+		return
+	}
+	character.statusEffects = append(character.statusEffects, statusEffect{
+		kind:      kind,
+		remaining: definition.duration,
+		tickTimer: definition.tickInterval,
+		stacks:    1,
+	})
+}
+
+// updateStatusEffects counts down each effect, returning the total damage (negative for healing) dealt this tick
+func (character *character) updateStatusEffects() int {
+	damage := 0
+	remaining := character.statusEffects[:0]
+	for _, effect := range character.statusEffects {
+		definition := statusDefinitions[effect.kind]
+		if definition.tickInterval > 0 {
+			effect.tickTimer--
+			if effect.tickTimer <= 0 {
+				effect.tickTimer = definition.tickInterval
+				damage += definition.damagePerTick * effect.stacks
+			}
+		}
+		effect.remaining--
+		if effect.remaining > 0 {
+			remaining = append(remaining, effect)
+		}
+	}
+	character.statusEffects = remaining
+	return damage
+}
+
+func (character *character) isStunned() bool {
+	for _, effect := range character.statusEffects {
+		if statusDefinitions[effect.kind].stun {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveSpeed is speed after every active status modifier, never below zero
+func (character *character) effectiveSpeed() int {
+	if character.isStunned() {
+		return 0
+	}
+	speed := character.speed
+	for _, effect := range character.statusEffects {
+		speed += statusDefinitions[effect.kind].speedModifier * effect.stacks
+	}
+	return max(speed, 0)
+}
+
+// effectiveAttackPower is attackPower after every active status modifier, never below zero
+func (character *character) effectiveAttackPower() int {
+	attack := character.attackPower
+	for _, effect := range character.statusEffects {
+		attack += statusDefinitions[effect.kind].attackModifier * effect.stacks
+	}
+	return max(attack, 0)
+}
+
+// updateAllStatusEffects ticks the statuses of the player and every living enemy, and applies hazard tiles
+func (game *rpgGame) updateAllStatusEffects() {
+	if game.player.action != DEAD {
+		hazard, tile, ok := game.hazardUnderPlayer()
+		if !ok {
+			game.player.hazardLevel = nil
+		} else if game.player.hazardLevel != game.levelCurrent || game.player.hazardTile != tile {
+			//only on stepping onto the tile, so standing on it doesn't stack the status every tick
+			game.player.hazardLevel, game.player.hazardTile = game.levelCurrent, tile
+			game.player.applyStatus(hazard)
+		}
+		if damage := game.player.updateStatusEffects(); damage > 0 {
+			if !game.godMode {
+				game.player.hitPoints -= damage
+				game.spawnStatusNumber(&game.player.character, game.levelCurrent, damage)
+			}
+		} else if healed := game.player.heal(-damage); healed > 0 {
+			game.spawnStatusNumber(&game.player.character, game.levelCurrent, -healed)
+		}
+	}
+	for i := range game.enemies {
+		if game.enemies[i].action == DEAD {
+			continue
+		}
+		if damage := game.enemies[i].updateStatusEffects(); damage > 0 {
+			game.enemies[i].hitPoints -= damage
+			game.spawnStatusNumber(&game.enemies[i], game.enemies[i].level, damage)
+			if game.enemies[i].hitPoints <= 0 {
+				game.enemies[i].death(game)
+			}
+		}
+	}
+}
+
+// hazardUnderPlayer looks through every layer of the tile under the player's feet for a hazard tile, and says which
+// tile that is
+func (game *rpgGame) hazardUnderPlayer() (int, image.Point, bool) {
+	tileX := game.player.centerX() / (game.levelCurrent.TileWidth * worldScale)
+	tileY := (game.player.yLoc + game.player.FRAME_HEIGHT*resizeScale - 1) / (game.levelCurrent.TileHeight * worldScale)
+	if tileX < 0 || tileY < 0 || tileX >= game.levelCurrent.Width || tileY >= game.levelCurrent.Height {
+		return NOSTATUS, image.Point{}, false
+	}
+	for _, layer := range game.levelCurrent.Layers {
+		tile := layer.Tiles[tileY*game.levelCurrent.Width+tileX]
+		if tile.IsNil() {
+			continue
+		}
+		if hazard, ok := tileHazard(tile); ok {
+			return hazard, image.Pt(tileX, tileY), true
+		}
+	}
+	return NOSTATUS, image.Point{}, false
+}
+
+// tileHazard is the status named by the hazard property of tile in its tileset, if it has one
+func tileHazard(tile *tiled.LayerTile) (int, bool) {
+	if tile.Tileset == nil {
+		return NOSTATUS, false
+	}
+	tilesetTile, err := tile.Tileset.GetTilesetTile(tile.ID)
+	if err != nil {
+		return NOSTATUS, false
+	}
+	return statusNamed(tilesetTile.Properties.GetString(HAZARDPROPERTY))
+}
+
+// statusNamed is the status kind called name in statusNames, the names the debug overlay shows
+func statusNamed(name string) (int, bool) {
+	for kind, statusName := range statusNames {
+		if statusName == name {
+			return kind, true
+		}
+	}
+	return NOSTATUS, false
+}
+
+func (game *rpgGame) spawnStatusNumber(target *character, level *tiled.Map, damage int) {
+	if damage < 0 {
+		game.spawnDamageNumber(target, level, -damage, colornames.Lightgreen)
+	} else {
+		game.spawnDamageNumber(target, level, damage, colornames.Purple)
+	}
+}
+
+// drawStatusIcons draws the player's active statuses in a row under the hearts from drawPlayerHealth
+func (game *rpgGame) drawStatusIcons(op *ebiten.DrawImageOptions, screen *ebiten.Image) {
+	for i, effect := range game.player.statusEffects {
+		x := i * HUDICONSIZE
+		y := game.healthRows() * HUDICONSIZE
+		op.GeoM.Reset()
+		op.GeoM.Translate(float64(x), float64(y))
+		op.ColorScale.Reset()
+		if tint := statusDefinitions[effect.kind].tint; tint != nil {
+			op.ColorScale.ScaleWithColor(tint)
+		}
+		screen.DrawImage(statusDefinitions[effect.kind].icon.(*ebiten.Image), op)
+		if effect.stacks > 1 {
+			DrawCenteredText(screen, game.fontSmall, strconv.Itoa(effect.stacks), x+HUDICONSIZE-2, y+HUDICONSIZE-2)
+		}
+	}
+	op.ColorScale.Reset()
+}
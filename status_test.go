@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestSteppingOnBonesPoisons(t *testing.T) {
+	game := newTestGame(t)
+	game.setLevel(0)
+	tileSize := game.levelCurrent.TileWidth * worldScale
+	//the skull in the south west of the dirt map
+	game.player.xLoc = 2*tileSize + tileSize/2 - game.player.FRAME_WIDTH*resizeScale/2
+	game.player.yLoc = 10*tileSize + tileSize/2 - game.player.FRAME_HEIGHT*resizeScale
+	game.player.statusEffects = nil
+	game.updateAllStatusEffects()
+	if len(game.player.statusEffects) != 1 || game.player.statusEffects[0].kind != POISON {
+		t.Fatalf("stepping onto the skull gave %v, want poison", game.player.statusEffects)
+	}
+	game.updateAllStatusEffects()
+	if stacks := game.player.statusEffects[0].stacks; stacks != 1 {
+		t.Errorf("standing on the skull for a second tick made %d stacks, want 1", stacks)
+	}
+}
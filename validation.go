@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"golang.org/x/image/colornames"
+	"image"
+	"strings"
+)
+
+// spriteSheetError is why objects.png couldn't be loaded while item pictures were being grabbed, if it couldn't
+var spriteSheetError error
+
+// requiredSounds are the effects the game plays by name
+var requiredSounds = []string{
+	"attackPowerUp", "enemyDeath", "enemyHit", "heal", "itemPickup", "levelUp", "playerDamaged", "playerInteract",
+	"questGiverTalk",
+}
+
+// missingImage is a magenta placeholder for a picture that failed to load
+func missingImage(width, height int) image.Image {
+	placeholder := ebiten.NewImage(width, height)
+	placeholder.Fill(colornames.Magenta)
+	return placeholder
+}
+
+// validateAssets checks the assets that are only loaded once they are needed, so a missing sound or song is
+// reported at startup rather than part way through a game
+func (game *rpgGame) validateAssets() []error {
+	var problems []error
+	if spriteSheetError != nil {
+		problems = append(problems, fmt.Errorf("item sprites: %w", spriteSheetError))
+	}
+	for _, name := range requiredSounds {
+		if _, ok := game.sounds.clips[name]; !ok {
+			problems = append(problems, fmt.Errorf("sound %s.wav is missing from assets/sounds", name))
+		}
+	}
+
+	tracks := []string{COMBATMUSIC}
+	for _, level := range game.levelMaps {
+		if track := levelMusic(level); track != "" {
+			tracks = append(tracks, track)
+		}
+	}
+	for _, track := range bossMusic {
+		tracks = append(tracks, track)
+	}
+	for _, name := range tracks {
+		track, err := game.sounds.openMusicTrack(name)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		track.close()
+	}
+	return problems
+}
+
+// errorReport is run in place of the game when validation fails, listing every problem found. It draws with the
+// debug font since the game's own font may be what failed to load.
+type errorReport struct {
+	problems []error
+}
+
+func (report *errorReport) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		return ebiten.Termination
+	}
+	return nil
+}
+
+func (report *errorReport) Draw(screen *ebiten.Image) {
+	lines := []string{"Micro RPG couldn't start because some of its assets are broken:", ""}
+	for _, problem := range report.problems {
+		lines = append(lines, "- "+problem.Error())
+	}
+	lines = append(lines, "", "Press Escape or Enter to quit.")
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), 8, 8)
+}
+
+func (report *errorReport) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return outsideWidth, outsideHeight
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"github.com/co0p/tankism/lib/collision"
+	"image"
+	"slices"
+)
+
+// weapon decides where and when the player's swing can hit, and how far they can reach to talk to or open things.
+// attackBoxes are indexed by direction and measured in unscaled sprite pixels from the player's top left corner.
+type weapon struct {
+	displayName   string
+	attackBoxes   [4]image.Rectangle
+	activeFrames  []int
+	swingDuration int
+	interactReach int
+}
+
+var SwordWeapon = weapon{
+	displayName: "Sword",
+	attackBoxes: [4]image.Rectangle{
+		DOWN:  image.Rect(-2, 28, 18, 42),
+		RIGHT: image.Rect(12, 10, 28, 30),
+		UP:    image.Rect(-2, -8, 18, 8),
+		LEFT:  image.Rect(-12, 10, 4, 30),
+	},
+	activeFrames:  []int{6, 5},
+	swingDuration: 24,
+	interactReach: 12,
+}
+
+// startAttack restarts the attack animation and forgets who the last swing already hit
+func (player *player) startAttack() {
+	player.frame = 7
+	player.frameDelay = 0
+	player.attackTimer = player.weapon.swingDuration
+	player.attackHits = player.attackHits[:0]
+}
+
+// isAttackActive is true while the swing is on one of the weapon's damaging animation frames
+func (player *player) isAttackActive() bool {
+	return player.action == INTERACT && player.attackTimer > 0 && slices.Contains(player.weapon.activeFrames, player.frame)
+}
+
+// getAttackBoundingBox returns the weapon hitbox for the direction the player is facing
+func (player *player) getAttackBoundingBox() collision.BoundingBox {
+	box := player.weapon.attackBoxes[player.direction]
+	return collision.BoundingBox{
+		X:      float64(player.xLoc + box.Min.X*resizeScale),
+		Y:      float64(player.yLoc + box.Min.Y*resizeScale),
+		Width:  float64(box.Dx() * resizeScale),
+		Height: float64(box.Dy() * resizeScale),
+	}
+}
+
+// getInteractionProbe returns a strip weapon.interactReach deep directly in front of the player, used for
+// talking and opening things rather than for hitting them
+func (player *player) getInteractionProbe() collision.BoundingBox {
+	reach := player.weapon.interactReach * resizeScale
+	width := player.FRAME_WIDTH * resizeScale
+	height := player.FRAME_HEIGHT * resizeScale
+	probe := collision.BoundingBox{X: float64(player.xLoc), Y: float64(player.yLoc), Width: float64(width), Height: float64(height)}
+	switch player.direction {
+	case DOWN:
+		probe.Y += float64(height)
+		probe.Height = float64(reach)
+	case RIGHT:
+		probe.X += float64(width)
+		probe.Width = float64(reach)
+	case UP:
+		probe.Y -= float64(reach)
+		probe.Height = float64(reach)
+	case LEFT:
+		probe.X -= float64(reach)
+		probe.Width = float64(reach)
+	}
+	return probe
+}
+
+// playerAttackCheck damages every enemy the current swing's hitbox overlaps, at most once per swing
+func (game *rpgGame) playerAttackCheck() {
+	if game.player.action != INTERACT {
+		game.player.attackTimer = 0
+	}
+	if game.player.attackTimer <= 0 {
+		return
+	}
+	game.player.attackTimer--
+	if !game.player.isAttackActive() {
+		return
+	}
+
+	attackBounds := game.player.getAttackBoundingBox()
+	for i := range game.enemies {
+		if game.enemies[i].level != game.levelCurrent || game.enemies[i].action == DEAD ||
+			slices.Contains(game.player.attackHits, i) {
+			continue
+		}
+		if collision.AABBCollision(attackBounds, game.enemies[i].getCollisionBoundingBox()) {
+			game.player.attackHits = append(game.player.attackHits, i)
+			game.damageEnemy(&game.enemies[i], game.player.effectiveAttackPower(), game.player.centerX(), game.player.centerY())
+		}
+	}
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/lafriks/go-tiled"
 	"github.com/solarlune/paths"
+	"image"
 	"path"
+	"slices"
 	"strings"
 )
 
+// levelFiles are the maps in assets, in the order of levelMaps
+var levelFiles = []string{"dirt.tmx", "island.tmx", "world.tmx"}
+
 type worldinfo struct {
 	levelCurrent          *tiled.Map
 	levelMaps             []*tiled.Map
@@ -18,9 +24,14 @@ type worldinfo struct {
 	pathFindingMaps       [][]string
 	pathGridCurrent       *paths.Grid
 	pathGrids             []*paths.Grid
+	checkpoints           []checkpoint
+	barrierRect           []image.Rectangle
+	teleporterRects       map[uint32]image.Rectangle
 }
 
-func initializeWorldInfo() *worldinfo {
+// initializeWorldInfo loads every map. It carries on past maps that can't be loaded so every broken one is reported
+// at once, but only returns a world if they all loaded.
+func initializeWorldInfo() (*worldinfo, error) {
 	tileMapHashes := make([]map[uint32]*ebiten.Image, 0, 5)
 	levelmaps := make([]*tiled.Map, 0, 5)
 	pathfindingmaps := make([][]string, 0, 5)
@@ -36,31 +47,108 @@ func initializeWorldInfo() *worldinfo {
 		pathGrids:             pathfindinggrids,
 	}
 
-	w.importTmx("dirt.tmx")
-	w.importTmx("island.tmx")
-	w.importTmx("world.tmx")
-	return &w
+	var problems []error
+	for _, filename := range levelFiles {
+		if err := w.importTmx(filename); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return &w, nil
 }
 
-func (w *worldinfo) importTmx(filename string) {
-	gameMap := loadMapFromEmbedded(path.Join("assets", filename))
-	ebitenImageMap := makeEbitenImagesFromMap(*gameMap)
+// loadedLevel is everything built from one map file
+type loadedLevel struct {
+	gameMap     *tiled.Map
+	tiles       map[uint32]*ebiten.Image
+	searchMap   []string
+	pathGrid    *paths.Grid
+	checkpoints []checkpoint
+}
 
-	w.levelMaps = append(w.levelMaps, gameMap)
-	w.levelCurrent = gameMap
-	w.tileHashCurrent = ebitenImageMap
-	w.tileHashes = append(w.tileHashes, ebitenImageMap)
+// loadLevel loads a map from assets along with its tile images, path grid and checkpoints
+func (w *worldinfo) loadLevel(filename string) (*loadedLevel, error) {
+	gameMap, err := loadMapFromEmbedded(path.Join(ASSETROOT, filename))
+	if err != nil {
+		return nil, err
+	}
+	if len(gameMap.Layers) < 2 {
+		return nil, fmt.Errorf("map %s needs a second layer for collision, it has %d", filename, len(gameMap.Layers))
+	}
+	ebitenImageMap, err := makeEbitenImagesFromMap(*gameMap)
+	if err != nil {
+		return nil, fmt.Errorf("map %s: %w", filename, err)
+	}
 
 	searchMap := w.makeSearchMap(gameMap)
-	w.pathFindingMapCurrent = searchMap
-	w.pathFindingMaps = append(w.pathFindingMaps, searchMap)
+	return &loadedLevel{
+		gameMap:     gameMap,
+		tiles:       ebitenImageMap,
+		searchMap:   searchMap,
+		pathGrid:    newPathGrid(searchMap, gameMap),
+		checkpoints: loadCheckpoints(gameMap),
+	}, nil
+}
 
+// loadHeadlessWorld loads every level without any images, for tools that look at the maps without a window, then
+// starts a new game on them so everything is where a player would find it. Each map is only path found on if
+// pathable says it can be, and a nil pathable leaves them all without a path grid. The errors line up with
+// levelFiles, and there is only a game if every map loaded.
+func loadHeadlessWorld(pathable func(filename string, gameMap *tiled.Map) bool) (*rpgGame, []error) {
+	w := worldinfo{}
+	problems := make([]error, len(levelFiles))
+	loaded := true
+	for i, filename := range levelFiles {
+		gameMap, err := loadMapFromEmbedded(path.Join(ASSETROOT, filename))
+		if err != nil {
+			problems[i] = err
+			loaded = false
+			continue
+		}
+		var searchMap []string
+		var grid *paths.Grid
+		if pathable != nil && pathable(filename, gameMap) {
+			searchMap = w.makeSearchMap(gameMap)
+			grid = newPathGrid(searchMap, gameMap)
+		}
+		w.levelMaps = append(w.levelMaps, gameMap)
+		w.tileHashes = append(w.tileHashes, nil)
+		w.pathFindingMaps = append(w.pathFindingMaps, searchMap)
+		w.pathGrids = append(w.pathGrids, grid)
+		w.checkpoints = append(w.checkpoints, loadCheckpoints(gameMap)...)
+	}
+	if !loaded {
+		return nil, problems
+	}
+	//the seed doesn't matter, nothing random happens before the game is looked at
+	game := &rpgGame{worldinfo: w, random: newGameRandom(1), settings: defaultSettings()}
+	game.startNewGame()
+	return game, problems
+}
+
+// importTmx loads a map and adds it to the end of levelMaps, making it the current level
+func (w *worldinfo) importTmx(filename string) error {
+	level, err := w.loadLevel(filename)
+	if err != nil {
+		return err
+	}
+	w.levelMaps = append(w.levelMaps, level.gameMap)
+	w.tileHashes = append(w.tileHashes, level.tiles)
+	w.pathFindingMaps = append(w.pathFindingMaps, level.searchMap)
+	w.pathGrids = append(w.pathGrids, level.pathGrid)
+	w.checkpoints = append(w.checkpoints, level.checkpoints...)
+	w.setLevel(len(w.levelMaps) - 1)
+	return nil
+}
+
+// newPathGrid turns a search map into the grid enemies path find on
+func newPathGrid(searchMap []string, gameMap *tiled.Map) *paths.Grid {
 	searchablePathMap := paths.NewGridFromStringArrays(searchMap, gameMap.TileWidth, gameMap.TileHeight)
 	searchablePathMap.SetWalkable('1', false)
 	searchablePathMap.SetWalkable('2', false)
-	w.pathGridCurrent = searchablePathMap
-	w.pathGrids = append(w.pathGrids, searchablePathMap)
-
+	return searchablePathMap
 }
 
 // makeSearchMap Takes a tiled.Map and returns a string array, which is used by the paths package
@@ -77,3 +165,39 @@ func (w *worldinfo) makeSearchMap(tiledMap *tiled.Map) []string {
 	mapAsStringSlice = append(mapAsStringSlice, row.String())
 	return mapAsStringSlice
 }
+
+// levelIndex returns the position of level in levelMaps, which is shared by tileHashes and pathGrids
+func (w *worldinfo) levelIndex(level *tiled.Map) int {
+	return slices.Index(w.levelMaps, level)
+}
+
+// setLevel makes the map at index in levelMaps the current one, along with its tiles, path grid and collision
+func (w *worldinfo) setLevel(index int) {
+	w.levelCurrent = w.levelMaps[index]
+	w.tileHashCurrent = w.tileHashes[index]
+	w.pathFindingMapCurrent = w.pathFindingMaps[index]
+	w.pathGridCurrent = w.pathGrids[index]
+	w.barrierRect, w.teleporterRects = levelCollision(w.levelCurrent)
+}
+
+// levelCollision finds the barrier and teleporter tiles on every layer of tiledMap, in map pixels. Collision comes
+// from the map rather than from drawing it, so the world plays the same whether or not it is drawn. Where a
+// teleporter ID is on more than one tile the last one found is used.
+func levelCollision(tiledMap *tiled.Map) ([]image.Rectangle, map[uint32]image.Rectangle) {
+	barriers := make([]image.Rectangle, 0)
+	teleporters := make(map[uint32]image.Rectangle)
+	for _, layer := range tiledMap.Layers {
+		for position, tile := range layer.Tiles {
+			x := position % tiledMap.Width * tiledMap.TileWidth
+			y := position / tiledMap.Width * tiledMap.TileHeight
+			tileRect := image.Rect(x, y, x+tiledMap.TileWidth, y+tiledMap.TileHeight)
+			if slices.Contains(barrierTileIDs, tile.ID) {
+				barriers = append(barriers, tileRect)
+			}
+			if slices.Contains(teleporterTileIDs, tile.ID) {
+				teleporters[tile.ID] = tileRect
+			}
+		}
+	}
+	return barriers, teleporters
+}